@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/linkcheck"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/urfave/cli/v3"
+)
+
+func createCheckLinksCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "check-links",
+		Before: requireConfig,
+		Usage:  "Request the expected destinations of route tests and the fixed redirect urls and report broken links",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "Maximum number of links checked at the same time",
+				Value: 4,
+				Validator: func(concurrency int64) error {
+					if concurrency <= 0 {
+						return fmt.Errorf("concurrency must be positive but was %d", concurrency)
+					}
+					return nil
+				},
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Maximum time to check a single link including redirects",
+				Value: 10 * time.Second,
+			},
+			&cli.StringSliceFlag{
+				Name:  "allow-host",
+				Usage: "Host links may be checked on, e.g. example.com or *.example.com. May be repeated. Links on other hosts are skipped. All hosts are allowed if not set",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			routes, err := parseRoutesFile(cmd.String("config"))
+			if err != nil {
+				return err
+			}
+
+			results := linkcheck.Check(ctx, route.Destinations(routes), linkcheck.Options{
+				Concurrency:  int(cmd.Int("concurrency")),
+				Timeout:      cmd.Duration("timeout"),
+				AllowedHosts: cmd.StringSlice("allow-host"),
+			})
+			if err := linkcheck.Write(cmd.Root().Writer, results); err != nil {
+				return fmt.Errorf("failed to write link report: %w", err)
+			}
+
+			if failed := linkcheck.Failed(results); failed > 0 {
+				return fmt.Errorf("broken links: %d of %d links failed", failed, len(results))
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/slightly-inconvenient/murl/internal/server"
+	"github.com/slightly-inconvenient/murl/internal/testreport"
+	"github.com/urfave/cli/v3"
+)
+
+func createDevCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "dev",
+		Before: requireConfig,
+		Usage:  "Serve the configured routes and documentation for local authoring, reloading them whenever the config file or the files it references change",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "address",
+				Usage: "Address to serve on. The server address and TLS settings of the config are ignored",
+				Value: "localhost:8080",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dev := server.NewDevServer()
+
+			watch := watcherFromContext(ctx)
+
+			// The reloads stop once serving stops
+			reloadCtx, cancelReloadCtx := context.WithCancel(ctx)
+			reloadDone := make(chan struct{})
+			go func() {
+				defer close(reloadDone)
+				for {
+					files := watchedFiles(cmd.String("config"))
+					states := fileStates(files)
+
+					serverConfig, handlers, err := loadDev(reloadCtx, cmd.Root().Writer, cmd.String("config"))
+					dev.Load(serverConfig, handlers, err)
+					if err != nil {
+						fmt.Fprintf(cmd.Root().Writer, "FAIL %s\n", err)
+					} else {
+						fmt.Fprintf(cmd.Root().Writer, "reloaded config at %s\n", time.Now().Format(time.TimeOnly))
+					}
+					watch.ran(err)
+
+					if !watch.waitForChange(reloadCtx, files, states) {
+						return
+					}
+				}
+			}()
+
+			err := dev.Run(ctx, cmd.String("address"))
+			cancelReloadCtx()
+			<-reloadDone
+			if err != nil {
+				return fmt.Errorf("failed to serve: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// loadDev parses the config file and creates the server config and route handlers from it.
+// Warnings and failed route tests are written to w but do not prevent serving the routes.
+func loadDev(ctx context.Context, w io.Writer, configPath string) (server.Config, []route.Handler, error) {
+	conf, err := config.ParseConfigFile(configPath)
+	if err != nil {
+		return server.Config{}, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	routes, err := route.NewRoutes(conf, route.SnapshotEnvironment())
+	if err != nil {
+		return server.Config{}, nil, fmt.Errorf("invalid routes: %w", err)
+	}
+
+	serverConfig, err := server.NewConfig(conf, routes)
+	if err != nil {
+		return server.Config{}, nil, fmt.Errorf("invalid server config: %w", err)
+	}
+
+	for _, warning := range route.Warnings(conf, routes, time.Now()) {
+		fmt.Fprintln(w, "warning:", warning)
+	}
+
+	handlers := route.NewHandlers(routes)
+	if results := route.RunTests(ctx, routes, handlers); len(results) > 0 {
+		if err := testreport.WriteFailures(w, results); err != nil {
+			return server.Config{}, nil, fmt.Errorf("failed to write test report: %w", err)
+		}
+	}
+
+	return serverConfig, handlers, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/urfave/cli/v3"
+)
+
+func createDiffCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Replay the test requests of two configurations through both and report the behavioural changes as markdown",
+		ArgsUsage: "old.yaml new.yaml",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "urls",
+				Usage: "Path to a file with additional request urls to replay, one per line. Empty lines and lines starting with # are ignored",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() != 2 {
+				return fmt.Errorf("expected an old and a new config file but got %d arguments", cmd.Args().Len())
+			}
+
+			oldRoutes, err := parseRoutesFile(cmd.Args().Get(0))
+			if err != nil {
+				return fmt.Errorf("invalid old config: %w", err)
+			}
+
+			newRoutes, err := parseRoutesFile(cmd.Args().Get(1))
+			if err != nil {
+				return fmt.Errorf("invalid new config: %w", err)
+			}
+
+			urls := []string{}
+			if path := cmd.String("urls"); path != "" {
+				urls, err = parseURLsFile(path)
+				if err != nil {
+					return fmt.Errorf("failed to parse urls file: %w", err)
+				}
+			}
+
+			diff, err := route.DiffRoutes(ctx, oldRoutes, newRoutes, urls)
+			if err != nil {
+				return fmt.Errorf("failed to diff routes: %w", err)
+			}
+
+			return writeDiffMarkdown(cmd.Root().Writer, diff)
+		},
+	}
+}
+
+func parseRoutesFile(path string) ([]route.Route, error) {
+	conf, err := config.ParseConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	routes, err := route.NewRoutes(conf, route.SnapshotEnvironment())
+	if err != nil {
+		return nil, fmt.Errorf("invalid routes: %w", err)
+	}
+
+	return routes, nil
+}
+
+func parseURLsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	urls := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+// writeDiffMarkdown writes the diff as markdown suitable for a pull request comment.
+func writeDiffMarkdown(w io.Writer, diff route.Diff) error {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "## Route changes\n\n")
+	if len(diff.AddedRoutes) == 0 && len(diff.RemovedRoutes) == 0 && len(diff.Changes) == 0 {
+		fmt.Fprintf(builder, "No behavioural changes in %d replayed requests.\n", diff.Requests)
+		_, err := io.WriteString(w, builder.String())
+		return err
+	}
+	fmt.Fprintf(builder, "%d of %d replayed requests changed.\n", len(diff.Changes), diff.Requests)
+
+	writeList := func(title string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		fmt.Fprintf(builder, "\n### %s\n\n", title)
+		for _, path := range paths {
+			fmt.Fprintf(builder, "- `%s`\n", path)
+		}
+	}
+	writeList("Added routes", diff.AddedRoutes)
+	writeList("Removed routes", diff.RemovedRoutes)
+
+	if len(diff.Changes) > 0 {
+		fmt.Fprintf(builder, "\n### Changed responses\n\n")
+		fmt.Fprintf(builder, "| Request | Before | After |\n")
+		fmt.Fprintf(builder, "| --- | --- | --- |\n")
+		for _, change := range diff.Changes {
+			fmt.Fprintf(builder, "| `%s %s` | %s | %s |\n", change.Method, markdownCell(change.URL), describeDiffResponse(change.Old), describeDiffResponse(change.New))
+		}
+	}
+
+	failing := []route.DiffChange{}
+	for _, change := range diff.Changes {
+		if change.NewlyFailingCheck() {
+			failing = append(failing, change)
+		}
+	}
+	if len(failing) > 0 {
+		fmt.Fprintf(builder, "\n### Newly failing checks\n\n")
+		for _, change := range failing {
+			fmt.Fprintf(builder, "- `%s %s` fails `%s` of route `%s`\n", change.Method, markdownCell(change.URL), markdownCell(change.New.FailedCheck), change.New.Route)
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func describeDiffResponse(response route.DiffResponse) string {
+	description := fmt.Sprintf("%d", response.Status)
+	if response.Location != "" {
+		description += fmt.Sprintf(" to `%s`", markdownCell(response.Location))
+	}
+	if response.FailedCheck != "" {
+		description += fmt.Sprintf(" failing `%s`", markdownCell(response.FailedCheck))
+	}
+	if response.Route == "" {
+		description += " (no route)"
+	} else {
+		description += fmt.Sprintf(" via `%s`", response.Route)
+	}
+
+	return description
+}
+
+// markdownCell escapes the value for use in a markdown table cell.
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/slightly-inconvenient/murl/internal/testreport"
+	"github.com/urfave/cli/v3"
+)
+
+func createLintCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "lint",
+		Before: requireConfig,
+		Usage:  fmt.Sprintf("Report route hygiene issues (%s)", strings.Join(route.LintRules, ", ")),
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: fmt.Sprintf("Lint report format (%s)", strings.Join(testreport.Formats, ", ")),
+				Value: testreport.FormatText,
+				Validator: func(format string) error {
+					if !slices.Contains(testreport.Formats, format) {
+						return fmt.Errorf("unsupported report format %q (supported are %s)", format, strings.Join(testreport.Formats, ", "))
+					}
+					return nil
+				},
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			conf, err := config.ParseConfigFile(cmd.String("config"))
+			if err != nil {
+				return fmt.Errorf("failed to parse config file: %w", err)
+			}
+
+			findings, err := route.Lint(conf)
+			if err != nil {
+				return fmt.Errorf("invalid routes: %w", err)
+			}
+
+			if err := testreport.WriteFindings(cmd.Root().Writer, cmd.String("format"), findings); err != nil {
+				return fmt.Errorf("failed to write lint report: %w", err)
+			}
+
+			if len(findings) > 0 {
+				return fmt.Errorf("lint findings: %d", len(findings))
+			}
+
+			return nil
+		},
+	}
+}
@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/slightly-inconvenient/murl/internal/config"
 	"github.com/slightly-inconvenient/murl/internal/route"
 	"github.com/slightly-inconvenient/murl/internal/server"
+	"github.com/slightly-inconvenient/murl/internal/testreport"
 	"github.com/urfave/cli/v3"
 )
 
@@ -27,13 +32,17 @@ func run(ctx context.Context) int {
 		Commands: []*cli.Command{
 			createServeCommand(),
 			createValidateCommand(),
+			createResolveCommand(),
+			createDiffCommand(),
+			createCheckLinksCommand(),
+			createLintCommand(),
+			createDevCommand(),
 		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "config",
-				Aliases:  []string{"c"},
-				Usage:    "Path to the configuration file",
-				Required: true,
+				Name:    "config",
+				Aliases: []string{"c"},
+				Usage:   "Path to the configuration file. Required by every command but diff",
 				Sources: cli.NewValueSourceChain(
 					cli.EnvVar("MURL_CONFIG"),
 				),
@@ -50,56 +59,264 @@ func run(ctx context.Context) int {
 	return 0
 }
 
+// requireConfig fails if the config flag is not set. It is not required by the root command itself to let diff,
+// which compares the config files given as arguments, run without it.
+func requireConfig(ctx context.Context, cmd *cli.Command) error {
+	if !cmd.IsSet("config") {
+		return fmt.Errorf("Required flag %q not set", "config")
+	}
+
+	return nil
+}
+
 func createServeCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "serve",
-		Usage: "Start a server to serve the configured redirect routes",
+		Name:   "serve",
+		Before: requireConfig,
+		Usage:  "Start a server to serve the configured redirect routes",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "record",
+				Usage: "Record served requests as route tests and write them as YAML to the path on shutdown",
+			},
+			&cli.IntFlag{
+				Name:  "record-samples",
+				Usage: "Maximum number of distinct requests recorded per route",
+				Value: 10,
+				Validator: func(samples int64) error {
+					if samples < 1 {
+						return fmt.Errorf("record samples must be at least 1 but was %d", samples)
+					}
+					return nil
+				},
+			},
+			&cli.StringSliceFlag{
+				Name:  "redact-header",
+				Usage: "Header whose values are replaced with " + route.RedactedValue + " in recorded tests. May be repeated",
+				Value: []string{"Authorization", "Proxy-Authorization", "Cookie"},
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			conf, err := config.ParseConfigFile(cmd.String("config"))
 			if err != nil {
 				return fmt.Errorf("failed to parse config file: %w", err)
 			}
 
-			serverConfig, err := server.NewConfig(conf.Server, conf.Routes)
+			routes, err := route.NewRoutes(conf, route.SnapshotEnvironment())
 			if err != nil {
-				return fmt.Errorf("invalid server config: %w", err)
+				return fmt.Errorf("invalid routes: %w", err)
 			}
 
-			routes, err := route.NewRoutes(conf.Routes)
+			serverConfig, err := server.NewConfig(conf, routes)
 			if err != nil {
-				return fmt.Errorf("invalid routes: %w", err)
+				return fmt.Errorf("invalid server config: %w", err)
+			}
+
+			handlers := route.NewHandlers(routes)
+			if !cmd.IsSet("record") {
+				if err := server.Run(ctx, serverConfig, handlers); err != nil {
+					return fmt.Errorf("failed to serve: %w", err)
+				}
+
+				return nil
 			}
 
-			if err := server.Run(ctx, serverConfig, route.NewHandlers(routes)); err != nil {
-				return fmt.Errorf("failed to serve: %w", err)
+			recorder := route.NewRecorder(routes, route.RecordOptions{
+				Samples:       int(cmd.Int("record-samples")),
+				RedactHeaders: cmd.StringSlice("redact-header"),
+			})
+			serveErr := server.Run(ctx, serverConfig, recorder.Handlers(handlers))
+			if serveErr != nil {
+				serveErr = fmt.Errorf("failed to serve: %w", serveErr)
 			}
 
-			return nil
+			// Recorded tests are written even if serving failed to keep the requests recorded until then
+			return errors.Join(serveErr, saveRecordedTests(cmd.String("record"), recorder.Tests()))
 		},
 	}
 }
 
+func saveRecordedTests(path string, recorded []route.RecordedTests) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create recorded tests file: %w", err)
+	}
+	defer file.Close()
+
+	if err := writeRecordedTests(file, recorded); err != nil {
+		return fmt.Errorf("failed to write recorded tests: %w", err)
+	}
+
+	return file.Close()
+}
+
 func createValidateCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "validate",
-		Usage: "Validate routes against tests defined in them",
+		Name:   "validate",
+		Before: requireConfig,
+		Usage:  "Validate routes against tests defined in them",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: fmt.Sprintf("Test report format (%s)", strings.Join(testreport.Formats, ", ")),
+				Value: testreport.FormatText,
+				Validator: func(format string) error {
+					if !slices.Contains(testreport.Formats, format) {
+						return fmt.Errorf("unsupported report format %q (supported are %s)", format, strings.Join(testreport.Formats, ", "))
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "coverage",
+				Usage: "Report which paths, params, check outcomes and redirect targets of each route are exercised by tests",
+			},
+			&cli.BoolFlag{
+				Name:  "mutate",
+				Usage: "Alter each check and redirect template literal of every route and report the alterations the tests of the route do not detect",
+			},
+			&cli.IntFlag{
+				Name:  "fuzz",
+				Usage: "Send the number of generated requests with unexpected input to every route and fail on template errors, malformed redirect urls and panics",
+				Validator: func(requests int64) error {
+					if requests < 0 {
+						return fmt.Errorf("fuzz requests must not be negative but was %d", requests)
+					}
+					return nil
+				},
+			},
+			&cli.UintFlag{
+				Name:  "fuzz-seed",
+				Usage: "Seed to generate fuzz requests from. The same seed generates the same requests",
+			},
+			&cli.StringFlag{
+				Name:  "target",
+				Usage: "Send the test requests over HTTP to the instance serving the routes at the base url instead of running them in-process",
+			},
+			&cli.StringFlag{
+				Name:  "target-ca",
+				Usage: "Path to a CA bundle to verify the target certificate against instead of the system roots",
+			},
+			&cli.StringFlag{
+				Name:  "target-cert",
+				Usage: "Path to a client certificate to present to the target. Requires --target-key",
+			},
+			&cli.StringFlag{
+				Name:  "target-key",
+				Usage: "Path to the key of the client certificate presented to the target",
+			},
+			&cli.FloatFlag{
+				Name:  "min-coverage",
+				Usage: "Fail if the percentage of route items exercised by tests is below the minimum. Implies --coverage",
+				Validator: func(minimum float64) error {
+					if minimum < 0 || minimum > 100 {
+						return fmt.Errorf("minimum coverage must be in range [0, 100] but was %v", minimum)
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "watch",
+				Usage: "Keep running and validate again whenever the config file or the files it references change",
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			conf, err := config.ParseConfigFile(cmd.String("config"))
-			if err != nil {
-				return fmt.Errorf("failed to parse config file: %w", err)
+			if cmd.Bool("watch") {
+				if cmd.String("format") != testreport.FormatText {
+					return fmt.Errorf("watch mode only supports the %q report format", testreport.FormatText)
+				}
+				return watchValidate(ctx, cmd)
 			}
 
-			routes, err := route.NewRoutes(conf.Routes)
-			if err != nil {
-				return fmt.Errorf("invalid routes: %w", err)
-			}
+			return validate(ctx, cmd, false)
+		},
+	}
+}
 
-			handlers := route.NewHandlers(routes)
-			if err := route.TestHandlers(ctx, routes, handlers); err != nil {
-				return fmt.Errorf("failed tests: %w", err)
-			}
+// validate validates the routes of the config file and runs their tests. Only failed tests are reported if concise.
+func validate(ctx context.Context, cmd *cli.Command, concise bool) error {
+	conf, err := config.ParseConfigFile(cmd.String("config"))
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
 
-			return nil
-		},
+	routes, err := route.NewRoutes(conf, route.SnapshotEnvironment())
+	if err != nil {
+		return fmt.Errorf("invalid routes: %w", err)
 	}
+
+	// Warnings and the coverage, mutation and fuzz reports are written to stderr for machine readable formats to keep the report parseable
+	warningWriter := cmd.Root().Writer
+	if cmd.String("format") != testreport.FormatText {
+		warningWriter = cmd.Root().ErrWriter
+	}
+	for _, warning := range route.Warnings(conf, routes, time.Now()) {
+		fmt.Fprintln(warningWriter, "warning:", warning)
+	}
+
+	var results []route.TestResult
+	var coverage []route.RouteCoverage
+	if cmd.IsSet("target") {
+		if cmd.Bool("coverage") || cmd.IsSet("min-coverage") {
+			return fmt.Errorf("coverage cannot be measured against a target")
+		}
+
+		target, err := parseTarget(cmd.String("target"))
+		if err != nil {
+			return err
+		}
+
+		client, err := newTargetClient(cmd.String("target-ca"), cmd.String("target-cert"), cmd.String("target-key"))
+		if err != nil {
+			return err
+		}
+
+		results = route.RunRemoteTests(ctx, routes, target, client)
+	} else {
+		results, coverage = route.RunTestsWithCoverage(ctx, routes, route.NewHandlers(routes))
+	}
+
+	var writeErr error
+	if concise {
+		writeErr = testreport.WriteFailures(cmd.Root().Writer, results)
+	} else {
+		writeErr = testreport.Write(cmd.Root().Writer, cmd.String("format"), results)
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write test report: %w", writeErr)
+	}
+
+	if cmd.Bool("coverage") || cmd.IsSet("min-coverage") {
+		if err := testreport.WriteCoverage(warningWriter, coverage); err != nil {
+			return fmt.Errorf("failed to write coverage report: %w", err)
+		}
+	}
+
+	if cmd.Bool("mutate") {
+		if err := testreport.WriteMutations(warningWriter, route.RunMutations(ctx, routes)); err != nil {
+			return fmt.Errorf("failed to write mutation report: %w", err)
+		}
+	}
+
+	fuzzFailures := []route.FuzzFailure{}
+	if requests := cmd.Int("fuzz"); requests > 0 {
+		fuzzFailures = route.Fuzz(ctx, routes, int(requests), cmd.Uint("fuzz-seed"))
+		if err := writeFuzzFailures(warningWriter, fuzzFailures); err != nil {
+			return fmt.Errorf("failed to write fuzz report: %w", err)
+		}
+	}
+
+	if failed := testreport.Failed(results); failed > 0 {
+		return fmt.Errorf("failed tests: %d of %d tests failed", failed, len(results))
+	}
+
+	if len(fuzzFailures) > 0 {
+		return fmt.Errorf("fuzzing failed: %d generated requests were handled incorrectly", len(fuzzFailures))
+	}
+
+	if minimum := cmd.Float("min-coverage"); testreport.CoveragePercent(coverage) < minimum {
+		return fmt.Errorf("coverage %.1f%% is below the minimum of %.1f%%", testreport.CoveragePercent(coverage), minimum)
+	}
+
+	return nil
 }
@@ -2,13 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/slightly-inconvenient/murl/internal/testtls"
 )
 
 func TestServe(t *testing.T) {
@@ -62,12 +70,646 @@ func TestServe(t *testing.T) {
 	}
 }
 
+func TestServeRecord(t *testing.T) {
+	t.Setenv("EXAMPLE_HOST", "localhost")
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	serverCtx, cancelServerCtx := context.WithCancel(ctx)
+	t.Cleanup(cancelServerCtx)
+
+	errCh := make(chan error, 1)
+	recordPath := filepath.Join(t.TempDir(), "tests.yaml")
+
+	go func() {
+		defer close(errCh)
+
+		os.Args = []string{"murl", "serve", "--config", filepath.Join("testdata", "config.yaml"), "--record", recordPath}
+		if result := run(serverCtx); result != 0 {
+			errCh <- fmt.Errorf("unexpected exit code: %d", result)
+		}
+	}()
+
+	client := http.Client{
+		Timeout: 1 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: http.DefaultTransport,
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	for {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatalf("server failed to start: %v", err)
+			}
+
+			recorded, err := os.ReadFile(recordPath)
+			if err != nil {
+				t.Fatalf("failed to read recorded tests: %v", err)
+			}
+			if !strings.Contains(string(recorded), "url: /example/test") {
+				t.Fatalf("expected the served request to be recorded but got %q", recorded)
+			}
+
+			return
+		case <-ctx.Done():
+			t.Fatalf("server did not start and record the test request in time")
+		case <-ticker.C:
+			resp, err := client.Get("http://localhost:8080/example/test")
+			if err != nil {
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusTemporaryRedirect {
+				cancelServerCtx()
+			}
+		}
+	}
+}
+
 func TestValidate(t *testing.T) {
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
 	t.Cleanup(cancelCtx)
 
-	os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml")}
+	for _, format := range []string{"text", "json", "junit"} {
+		os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml"), "--format", format}
+		if result := run(ctx); result != 0 {
+			t.Fatalf("unexpected exit code with format %q: %d", format, result)
+		}
+	}
+
+	os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml"), "--format", "tap"}
+	if result := run(ctx); result != 1 {
+		t.Fatalf("expected exit code 1 with unsupported format but got %d", result)
+	}
+
+	os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml"), "--coverage", "--min-coverage", "50"}
+	if result := run(ctx); result != 0 {
+		t.Fatalf("unexpected exit code with coverage above minimum: %d", result)
+	}
+
+	os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml"), "--mutate"}
+	if result := run(ctx); result != 0 {
+		t.Fatalf("unexpected exit code with mutation testing: %d", result)
+	}
+
+	os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml"), "--fuzz", "50"}
+	if result := run(ctx); result != 0 {
+		t.Fatalf("unexpected exit code with fuzzing: %d", result)
+	}
+
+	os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml"), "--min-coverage", "100"}
+	if result := run(ctx); result != 1 {
+		t.Fatalf("expected exit code 1 with coverage below minimum but got %d", result)
+	}
+}
+
+func TestValidateFuzz(t *testing.T) {
+	// The example redirect url does not escape the path so unexpected input reaches the Location header once the host check passes
+	t.Setenv("EXAMPLE_HOST", "localhost")
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	os.Args = []string{"murl", "validate", "--config", filepath.Join("testdata", "config.yaml"), "--fuzz", "200", "--fuzz-seed", "1"}
+	if result := run(ctx); result != 1 {
+		t.Fatalf("expected exit code 1 with fuzz failures but got %d", result)
+	}
+}
+
+func TestValidateWatch(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(expected string) {
+		content := "routes:\n  - path: /example\n    redirect:\n      url: https://example.com\n    tests:\n      - request:\n          url: /example\n        response:\n          url: " + expected + "\n"
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+	writeConfig("https://example.com")
+
+	watchCtx, cancelWatchCtx := context.WithCancel(ctx)
+	t.Cleanup(cancelWatchCtx)
+	watchCtx, ticks, ran := driveWatch(watchCtx)
+
+	resultCh := make(chan int, 1)
+	go func() {
+		os.Args = []string{"murl", "--config", configPath, "validate", "--watch"}
+		resultCh <- run(watchCtx)
+	}()
+
+	if err := awaitWatchRun(ctx, t, ran); err != nil {
+		t.Fatalf("expected the initial validation to pass but got error: %v", err)
+	}
+
+	// The watcher keeps running and validates the changed config which now fails
+	writeConfig("https://example.com/changed")
+	pollWatch(ctx, t, ticks)
+	if err := awaitWatchRun(ctx, t, ran); err == nil {
+		t.Fatalf("expected the validation of the changed config to fail")
+	}
+	cancelWatchCtx()
+
+	select {
+	case result := <-resultCh:
+		if result != 1 {
+			t.Fatalf("expected exit code 1 after the changed config failed validation but got %d", result)
+		}
+	case <-ctx.Done():
+		t.Fatalf("watcher did not stop in time")
+	}
+
+	os.Args = []string{"murl", "--config", configPath, "validate", "--watch", "--format", "json"}
+	if result := run(ctx); result != 1 {
+		t.Fatalf("expected exit code 1 with json format but got %d", result)
+	}
+}
+
+// driveWatch returns a context making the watcher check the watched files for changes on the returned ticks channel
+// and report the result of every validation or reload on the returned channel.
+func driveWatch(ctx context.Context) (context.Context, chan<- time.Time, <-chan error) {
+	ticks, ran := make(chan time.Time), make(chan error)
+	ctx = withWatcher(ctx, watcher{
+		ticks: func() (<-chan time.Time, func()) {
+			return ticks, func() {}
+		},
+		ran: func(err error) {
+			ran <- err
+		},
+	})
+
+	return ctx, ticks, ran
+}
+
+// pollWatch makes the watcher check the watched files for changes once.
+func pollWatch(ctx context.Context, t *testing.T, ticks chan<- time.Time) {
+	t.Helper()
+	select {
+	case ticks <- time.Now():
+	case <-ctx.Done():
+		t.Fatalf("watcher did not poll for changes in time")
+	}
+}
+
+// awaitWatchRun returns the result of the next validation or reload of the watcher.
+func awaitWatchRun(ctx context.Context, t *testing.T, ran <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-ran:
+		return err
+	case <-ctx.Done():
+		t.Fatalf("watcher did not run in time")
+		return nil
+	}
+}
+
+func TestDev(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(redirect string) {
+		content := "server:\n  address: localhost:8090\nroutes:\n  - path: /example\n    documentation:\n      title: Example\n    redirect:\n      url: " + redirect + "\n"
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+	writeConfig("https://example.com")
+
+	devCtx, cancelDevCtx := context.WithCancel(ctx)
+	t.Cleanup(cancelDevCtx)
+	devCtx, ticks, ran := driveWatch(devCtx)
+
+	resultCh := make(chan int, 1)
+	go func() {
+		os.Args = []string{"murl", "--config", configPath, "dev", "--address", "localhost:8090"}
+		resultCh <- run(devCtx)
+	}()
+
+	client := http.Client{
+		Timeout: 1 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	waitForStatus := func(path string, status int, content string) {
+		t.Helper()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("dev server did not respond with %d containing %q to %s in time", status, content, path)
+			case <-ticker.C:
+				resp, err := client.Get("http://localhost:8090" + path)
+				if err != nil {
+					continue
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode == status && strings.Contains(string(body), content) {
+					return
+				}
+			}
+		}
+	}
+
+	if err := awaitWatchRun(ctx, t, ran); err != nil {
+		t.Fatalf("expected the initial config to load but got error: %v", err)
+	}
+	waitForStatus("/example", http.StatusTemporaryRedirect, "")
+	waitForStatus("/", http.StatusOK, "/_murl/dev/version")
+
+	// An invalid config is shown as an error page instead of stopping the server
+	writeConfig("'{{ .invalid'")
+	pollWatch(ctx, t, ticks)
+	if err := awaitWatchRun(ctx, t, ran); err == nil {
+		t.Fatalf("expected the invalid config to fail to load")
+	}
+	waitForStatus("/example", http.StatusInternalServerError, "invalid routes")
+
+	writeConfig("https://example.com/fixed")
+	pollWatch(ctx, t, ticks)
+	if err := awaitWatchRun(ctx, t, ran); err != nil {
+		t.Fatalf("expected the fixed config to load but got error: %v", err)
+	}
+	waitForStatus("/example", http.StatusTemporaryRedirect, "")
+
+	cancelDevCtx()
+	select {
+	case result := <-resultCh:
+		if result != 0 {
+			t.Fatalf("unexpected exit code: %d", result)
+		}
+	case <-ctx.Done():
+		t.Fatalf("dev server did not stop in time")
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	dir := t.TempDir()
+	caFile, certFile, keyFile, clientCertFile, clientKeyFile := testtls.CreateTestMTLSCertificates(dir)
+
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(`routes:
+  - path: /example/{id}
+    params:
+      id: '{{.GetPath "id"}}'
+    redirect:
+      url: https://example.com/{{.id}}
+    tests:
+      - request:
+          url: /example/1
+        response:
+          url: https://example.com/1
+      - request:
+          url: /example/2
+          environment:
+            EXAMPLE_HOST: localhost
+        response:
+          url: https://localhost/2
+`), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	conf, err := config.ParseConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to parse config file: %v", err)
+	}
+	routes, err := route.NewRoutes(conf, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create routes: %v", err)
+	}
+	mux := http.NewServeMux()
+	for _, handler := range route.NewHandlers(routes) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	serverCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %v", err)
+	}
+	caBytes, err := os.ReadFile(caFile)
+	if err != nil {
+		t.Fatalf("failed to read CA file: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(caBytes)
+
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		description  string
+		args         []string
+		expectedCode int
+	}{
+		{
+			description:  "passes with CA and client certificate",
+			args:         []string{"--target", server.URL, "--target-ca", caFile, "--target-cert", clientCertFile, "--target-key", clientKeyFile},
+			expectedCode: 0,
+		},
+		{
+			description:  "fails without client certificate",
+			args:         []string{"--target", server.URL, "--target-ca", caFile},
+			expectedCode: 1,
+		},
+		{
+			description:  "fails without CA",
+			args:         []string{"--target", server.URL, "--target-cert", clientCertFile, "--target-key", clientKeyFile},
+			expectedCode: 1,
+		},
+		{
+			description:  "fails with relative target",
+			args:         []string{"--target", "links.example.com"},
+			expectedCode: 1,
+		},
+		{
+			description:  "fails with coverage",
+			args:         []string{"--target", server.URL, "--coverage"},
+			expectedCode: 1,
+		},
+	}
+
+	for _, test := range tests {
+		os.Args = append([]string{"murl", "--config", configPath, "validate"}, test.args...)
+		if result := run(ctx); result != test.expectedCode {
+			t.Fatalf("expected exit code %d when test %q but got %d", test.expectedCode, test.description, result)
+		}
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	// The example destinations are skipped as they are not on an allowed host
+	os.Args = []string{"murl", "--config", filepath.Join("testdata", "config.yaml"), "check-links", "--allow-host", "example.com", "--timeout", "1s"}
+	if result := run(ctx); result != 0 {
+		t.Fatalf("unexpected exit code: %d", result)
+	}
+
+	os.Args = []string{"murl", "--config", filepath.Join("testdata", "config.yaml"), "check-links", "--concurrency", "0"}
+	if result := run(ctx); result != 1 {
+		t.Fatalf("expected exit code 1 with invalid concurrency but got %d", result)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	tests := []struct {
+		description  string
+		args         []string
+		expectedCode int
+	}{
+		{
+			description:  "resolves request",
+			args:         []string{"--env", "EXAMPLE_HOST=localhost", "--header", "X-ABC=1", "/example2/abc?query=x"},
+			expectedCode: 0,
+		},
+		{
+			description:  "resolves request as json",
+			args:         []string{"--json", "/example/abc"},
+			expectedCode: 0,
+		},
+		{
+			description:  "fails without url",
+			args:         []string{},
+			expectedCode: 1,
+		},
+		{
+			description:  "fails with invalid header",
+			args:         []string{"--header", "X-ABC", "/example/abc"},
+			expectedCode: 1,
+		},
+		{
+			description:  "fails with relative url",
+			args:         []string{"example/abc"},
+			expectedCode: 1,
+		},
+	}
+
+	for _, test := range tests {
+		os.Args = append([]string{"murl", "--config", filepath.Join("testdata", "config.yaml"), "resolve"}, test.args...)
+		if result := run(ctx); result != test.expectedCode {
+			t.Fatalf("expected exit code %d when test %q but got %d", test.expectedCode, test.description, result)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	configPath := filepath.Join("testdata", "config.yaml")
+	urlsPath := filepath.Join(t.TempDir(), "urls.txt")
+	if err := os.WriteFile(urlsPath, []byte("# corpus\n/example/abc\n\n/unknown\n"), 0o600); err != nil {
+		t.Fatalf("failed to write urls file: %v", err)
+	}
+
+	os.Args = []string{"murl", "diff", "--urls", urlsPath, configPath, configPath}
 	if result := run(ctx); result != 0 {
 		t.Fatalf("unexpected exit code: %d", result)
 	}
+
+	os.Args = []string{"murl", "diff", configPath}
+	if result := run(ctx); result != 1 {
+		t.Fatalf("expected exit code 1 with a single config but got %d", result)
+	}
+}
+
+func TestConfigRequired(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	for _, command := range []string{"serve", "validate", "resolve", "check-links", "lint", "dev"} {
+		os.Args = []string{"murl", command}
+		if result := run(ctx); result != 1 {
+			t.Fatalf("expected exit code 1 for %s without config but got %d", command, result)
+		}
+	}
+
+	t.Setenv("MURL_CONFIG", filepath.Join("testdata", "config.yaml"))
+	os.Args = []string{"murl", "validate"}
+	if result := run(ctx); result != 0 {
+		t.Fatalf("expected the config to be read from the environment but got exit code %d", result)
+	}
+}
+
+func TestLint(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancelCtx)
+
+	for _, format := range []string{"text", "json", "junit"} {
+		os.Args = []string{"murl", "--config", filepath.Join("testdata", "config.yaml"), "lint", "--format", format}
+		if result := run(ctx); result != 0 {
+			t.Fatalf("unexpected exit code with format %q: %d", format, result)
+		}
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("routes:\n  - path: /example\n    redirect:\n      url: https://example.com\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	os.Args = []string{"murl", "--config", configPath, "lint"}
+	if result := run(ctx); result != 1 {
+		t.Fatalf("expected exit code 1 with lint findings but got %d", result)
+	}
+}
+
+func TestWriteDiffMarkdown(t *testing.T) {
+	diff := route.Diff{
+		AddedRoutes:   []string{"/new"},
+		RemovedRoutes: []string{"/old"},
+		Requests:      3,
+		Changes: []route.DiffChange{
+			{
+				Method: "GET",
+				URL:    "/issue/2",
+				Old:    route.DiffResponse{Route: "/issue/{id}", Status: 307, Location: "https://example.com/2"},
+				New:    route.DiffResponse{Route: "/issue/{id}", Status: 400, FailedCheck: `id != "2" || id == "3"`},
+			},
+			{
+				Method: "GET",
+				URL:    "/old",
+				Old:    route.DiffResponse{Route: "/old", Status: 307, Location: "https://example.com/old"},
+				New:    route.DiffResponse{Status: 404},
+			},
+		},
+	}
+
+	expectedOutput := "## Route changes\n\n" +
+		"2 of 3 replayed requests changed.\n\n" +
+		"### Added routes\n\n- `/new`\n\n" +
+		"### Removed routes\n\n- `/old`\n\n" +
+		"### Changed responses\n\n" +
+		"| Request | Before | After |\n" +
+		"| --- | --- | --- |\n" +
+		"| `GET /issue/2` | 307 to `https://example.com/2` via `/issue/{id}` | 400 failing `id != \"2\" \\|\\| id == \"3\"` via `/issue/{id}` |\n" +
+		"| `GET /old` | 307 to `https://example.com/old` via `/old` | 404 (no route) |\n\n" +
+		"### Newly failing checks\n\n" +
+		"- `GET /issue/2` fails `id != \"2\" \\|\\| id == \"3\"` of route `/issue/{id}`\n"
+
+	builder := &strings.Builder{}
+	if err := writeDiffMarkdown(builder, diff); err != nil {
+		t.Fatalf("expected write to succeed but got error: %v", err)
+	}
+	if builder.String() != expectedOutput {
+		t.Fatalf("expected output\n%s\nbut got\n%s", expectedOutput, builder.String())
+	}
+}
+
+func TestWriteFuzzFailures(t *testing.T) {
+	failures := []route.FuzzFailure{
+		{
+			RouteIndex: 1,
+			Path:       "/issue/{id}",
+			Message:    "redirect url contains CR or LF",
+			Test: config.RouteTest{
+				Request: config.RouteTestRequest{
+					URL:     "/issue/%0D%0A",
+					Headers: map[string]string{"X-Lang": "en"},
+				},
+				Response: config.RouteTestResponse{Status: 400, NoRedirect: true, Body: route.FuzzExpectedBody},
+			},
+		},
+	}
+
+	buffer := &strings.Builder{}
+	if err := writeFuzzFailures(buffer, failures); err != nil {
+		t.Fatalf("expected write to succeed but got error: %v", err)
+	}
+
+	expectedOutput := `FUZZ route "/issue/{id}" at index [1]: redirect url contains CR or LF
+    reproduce by adding the test to the route and adjusting the expected response:
+    - request:
+        url: /issue/%0D%0A
+        headers:
+          X-Lang: en
+      response:
+        status: 400
+        no_redirect: true
+        body: replace with the error message rejecting the input
+1 fuzz failures
+`
+	if buffer.String() != expectedOutput {
+		t.Fatalf("expected output\n%s\nbut got\n%s", expectedOutput, buffer.String())
+	}
+}
+
+func TestWriteRecordedTests(t *testing.T) {
+	bucket := 1
+	recorded := []route.RecordedTests{
+		{
+			RouteIndex: 0,
+			Path:       "/search",
+			Title:      "Search",
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/search?q=go", Headers: map[string]string{"X-Token": "REDACTED"}},
+					Response: config.RouteTestResponse{URL: "https://example.com/?q=go"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/search"},
+					Response: config.RouteTestResponse{Status: 400},
+				},
+			},
+		},
+		{
+			RouteIndex: 2,
+			Path:       "/docs",
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/docs", Bucket: &bucket},
+					Response: config.RouteTestResponse{URL: "https://example.com/docs"},
+				},
+			},
+		},
+	}
+
+	buffer := &strings.Builder{}
+	if err := writeRecordedTests(buffer, recorded); err != nil {
+		t.Fatalf("expected write to succeed but got error: %v", err)
+	}
+
+	expectedOutput := `# tests recorded for route "/search" at index [0] (Search)
+- request:
+    url: /search?q=go
+    headers:
+      X-Token: REDACTED
+  response:
+    url: https://example.com/?q=go
+- request:
+    url: /search
+  response:
+    status: 400
+
+# tests recorded for route "/docs" at index [2]
+- request:
+    url: /docs
+    bucket: 1
+  response:
+    url: https://example.com/docs
+`
+	if buffer.String() != expectedOutput {
+		t.Fatalf("expected output\n%s\nbut got\n%s", expectedOutput, buffer.String())
+	}
 }
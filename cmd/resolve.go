@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/urfave/cli/v3"
+)
+
+func createResolveCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "resolve",
+		Before:    requireConfig,
+		Usage:     "Resolve a request against the routes and print how it was evaluated",
+		ArgsUsage: "/path?query",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "header",
+				Usage: "Request header as key=value. May be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  "env",
+				Usage: "Environment variable as KEY=VALUE taking precedence over the process environment. May be repeated",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the evaluation as JSON",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() != 1 {
+				return fmt.Errorf("expected exactly one request url but got %d", cmd.Args().Len())
+			}
+
+			headers, err := parseKeyValues(cmd.StringSlice("header"))
+			if err != nil {
+				return fmt.Errorf("invalid header: %w", err)
+			}
+
+			env, err := parseKeyValues(cmd.StringSlice("env"))
+			if err != nil {
+				return fmt.Errorf("invalid env: %w", err)
+			}
+
+			conf, err := config.ParseConfigFile(cmd.String("config"))
+			if err != nil {
+				return fmt.Errorf("failed to parse config file: %w", err)
+			}
+
+			routes, err := route.NewRoutes(conf, route.SnapshotEnvironment())
+			if err != nil {
+				return fmt.Errorf("invalid routes: %w", err)
+			}
+
+			trace, err := route.Resolve(ctx, routes, route.ResolveRequest{
+				URL:         cmd.Args().First(),
+				Headers:     headers,
+				Environment: route.NewEnvironment(env),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to resolve request: %w", err)
+			}
+
+			if cmd.Bool("json") {
+				return writeTraceJSON(cmd.Root().Writer, trace)
+			}
+
+			return writeTraceText(cmd.Root().Writer, trace)
+		},
+	}
+}
+
+func parseKeyValues(values []string) (map[string]string, error) {
+	result := make(map[string]string, len(values))
+	for _, entry := range values {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q must be in the form key=value", entry)
+		}
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+func writeTraceText(w io.Writer, trace route.Trace) error {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "%s %s\n", trace.Method, trace.URL)
+	if trace.Route == nil {
+		fmt.Fprintf(builder, "no route matched\n")
+	} else {
+		fmt.Fprintf(builder, "route: %q at index [%d]", trace.Route.Path, trace.Route.Index)
+		if trace.Route.Title != "" {
+			fmt.Fprintf(builder, " (%s)", trace.Route.Title)
+		}
+		fmt.Fprintf(builder, "\npattern: %s\n", trace.Route.Pattern)
+	}
+
+	for _, param := range trace.Params {
+		if len(param.Sources) == 0 {
+			fmt.Fprintf(builder, "param %q = %q (constant)\n", param.Name, param.Value)
+			continue
+		}
+		fmt.Fprintf(builder, "param %q = %q from %s\n", param.Name, param.Value, strings.Join(param.Sources, ", "))
+	}
+
+	for _, check := range trace.Checks {
+		result := "passed"
+		if !check.Passed {
+			result = "failed"
+		}
+		fmt.Fprintf(builder, "check [%d] %s: %s\n", check.Index, result, check.Expr)
+	}
+
+	if trace.Target != "" {
+		fmt.Fprintf(builder, "target: %q\n", trace.Target)
+	}
+
+	if trace.Location != "" {
+		fmt.Fprintf(builder, "destination: %s (status %d)\n", trace.Location, trace.Status)
+	} else {
+		fmt.Fprintf(builder, "error: %s (status %d)\n", trace.Body, trace.Status)
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+type jsonTrace struct {
+	Method   string           `json:"method"`
+	URL      string           `json:"url"`
+	Route    *jsonTraceRoute  `json:"route"`
+	Params   []jsonTraceParam `json:"params"`
+	Checks   []jsonTraceCheck `json:"checks"`
+	Target   string           `json:"target,omitempty"`
+	Status   int              `json:"status"`
+	Location string           `json:"location,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+type jsonTraceRoute struct {
+	Index   int    `json:"index"`
+	Path    string `json:"path"`
+	Title   string `json:"title,omitempty"`
+	Pattern string `json:"pattern"`
+}
+
+type jsonTraceParam struct {
+	Name     string   `json:"name"`
+	Sources  []string `json:"sources"`
+	Template string   `json:"template"`
+	Value    string   `json:"value"`
+}
+
+type jsonTraceCheck struct {
+	Index  int    `json:"index"`
+	Expr   string `json:"expr"`
+	Passed bool   `json:"passed"`
+}
+
+func writeTraceJSON(w io.Writer, trace route.Trace) error {
+	result := jsonTrace{
+		Method:   trace.Method,
+		URL:      trace.URL,
+		Params:   make([]jsonTraceParam, 0, len(trace.Params)),
+		Checks:   make([]jsonTraceCheck, 0, len(trace.Checks)),
+		Target:   trace.Target,
+		Status:   trace.Status,
+		Location: trace.Location,
+	}
+	if trace.Route != nil {
+		result.Route = &jsonTraceRoute{
+			Index:   trace.Route.Index,
+			Path:    trace.Route.Path,
+			Title:   trace.Route.Title,
+			Pattern: trace.Route.Pattern,
+		}
+	}
+	for _, param := range trace.Params {
+		sources := param.Sources
+		if sources == nil {
+			sources = []string{}
+		}
+		result.Params = append(result.Params, jsonTraceParam{Name: param.Name, Sources: sources, Template: param.Template, Value: param.Value})
+	}
+	for _, check := range trace.Checks {
+		result.Checks = append(result.Checks, jsonTraceCheck{Index: check.Index, Expr: check.Expr, Passed: check.Passed})
+	}
+	if trace.Location == "" {
+		result.Error = trace.Body
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// targetTimeout is the maximum time a single test request sent to a remote target may take.
+const targetTimeout = 30 * time.Second
+
+// parseTarget parses the base url of a remote instance the routes are served at.
+func parseTarget(target string) (*url.URL, error) {
+	result, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse target %q: %w", target, err)
+	}
+	if (result.Scheme != "http" && result.Scheme != "https") || result.Host == "" {
+		return nil, fmt.Errorf("target %q must be an absolute http or https url", target)
+	}
+	if result.RawQuery != "" || result.Fragment != "" {
+		return nil, fmt.Errorf("target %q must not have a query or fragment", target)
+	}
+
+	return result, nil
+}
+
+// newTargetClient creates the client test requests are sent to a remote target with.
+// The server certificate is verified against the CA bundle if given and the system roots otherwise.
+// The client certificate and key are presented if given.
+func newTargetClient(caFile string, certFile string, keyFile string) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		content, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read target CA file at path %q: %w", caFile, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("target CA file at path %q contains no PEM encoded certificates", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("target client certificate and key must be provided together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load target client certificate at path %q with key at path %q: %w", certFile, keyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: targetTimeout}, nil
+}
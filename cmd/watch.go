@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+// clearScreen moves the cursor to the top left corner and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// watchInterval is how often the watched files are checked for changes.
+const watchInterval = 500 * time.Millisecond
+
+// watcher checks the watched files for changes on every tick and is notified of the result of every validation or reload of them.
+type watcher struct {
+	// ticks returns the channel signalling when to check the watched files for changes and a function stopping it.
+	ticks func() (<-chan time.Time, func())
+	// ran is called with the result of every validation or reload of the watched files.
+	ran func(err error)
+}
+
+type watcherContextKey struct{}
+
+// withWatcher replaces the watcher of the watch and dev commands. Used by tests to drive the checks and wait for the validations.
+func withWatcher(ctx context.Context, w watcher) context.Context {
+	return context.WithValue(ctx, watcherContextKey{}, w)
+}
+
+// watcherFromContext returns the watcher set with withWatcher or one checking the files every watchInterval.
+func watcherFromContext(ctx context.Context) watcher {
+	if w, ok := ctx.Value(watcherContextKey{}).(watcher); ok {
+		return w
+	}
+
+	return watcher{
+		ticks: func() (<-chan time.Time, func()) {
+			ticker := time.NewTicker(watchInterval)
+			return ticker.C, ticker.Stop
+		},
+		ran: func(err error) {},
+	}
+}
+
+// watchValidate validates the routes and validates them again whenever the config file or the files it references change
+// until the context is done. Returns the error of the last validation.
+func watchValidate(ctx context.Context, cmd *cli.Command) error {
+	w := cmd.Root().Writer
+	watch := watcherFromContext(ctx)
+	for {
+		files := watchedFiles(cmd.String("config"))
+		states := fileStates(files)
+
+		fmt.Fprint(w, clearScreen)
+		err := validate(ctx, cmd, true)
+		if err != nil {
+			fmt.Fprintf(w, "FAIL %s\n", err)
+		} else {
+			fmt.Fprintf(w, "PASS\n")
+		}
+		fmt.Fprintf(w, "watching %d files for changes at %s, press ctrl+c to stop\n", len(files), time.Now().Format(time.TimeOnly))
+		watch.ran(err)
+
+		if !watch.waitForChange(ctx, files, states) {
+			return err
+		}
+	}
+}
+
+// watchedFiles returns the config file and the files it references. Only the config file is watched if it fails to parse.
+func watchedFiles(configPath string) []string {
+	conf, err := config.ParseConfigFile(configPath)
+	if err != nil {
+		return []string{configPath}
+	}
+
+	return append([]string{configPath}, conf.ReferencedFiles()...)
+}
+
+// fileStates returns the modification time and size of every file. Missing files have an empty state.
+func fileStates(files []string) map[string]string {
+	result := make(map[string]string, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			result[file] = ""
+			continue
+		}
+		result[file] = fmt.Sprintf("%s %d", info.ModTime().Format(time.RFC3339Nano), info.Size())
+	}
+
+	return result
+}
+
+// waitForChange blocks until the state of any of the files differs from the states or the context is done.
+// Returns false if the context is done.
+func (s watcher) waitForChange(ctx context.Context, files []string, states map[string]string) bool {
+	ticks, stop := s.ticks()
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticks:
+			if !maps.Equal(fileStates(files), states) {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"gopkg.in/yaml.v3"
+)
+
+// writeFuzzFailures writes the fuzz failures with a route test reproducing each to add to the tests of the route.
+func writeFuzzFailures(w io.Writer, failures []route.FuzzFailure) error {
+	builder := &strings.Builder{}
+	for _, failure := range failures {
+		content := &strings.Builder{}
+		encoder := yaml.NewEncoder(content)
+		encoder.SetIndent(2)
+		if err := encoder.Encode([]config.RouteTest{failure.Test}); err != nil {
+			return fmt.Errorf("failed to marshal reproducing test: %w", err)
+		}
+
+		fmt.Fprintf(builder, "FUZZ route %q at index [%d]: %s\n", failure.Path, failure.RouteIndex, failure.Message)
+		fmt.Fprintf(builder, "    reproduce by adding the test to the route and adjusting the expected response:\n")
+		for _, line := range strings.Split(strings.TrimRight(content.String(), "\n"), "\n") {
+			fmt.Fprintf(builder, "    %s\n", line)
+		}
+	}
+	fmt.Fprintf(builder, "%d fuzz failures\n", len(failures))
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// writeRecordedTests writes the recorded tests of every route as YAML ready to add to the tests of the route.
+func writeRecordedTests(w io.Writer, recorded []route.RecordedTests) error {
+	builder := &strings.Builder{}
+	for idx, routeTests := range recorded {
+		encoder := yaml.NewEncoder(builder)
+		encoder.SetIndent(2)
+		if idx > 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(builder, "# tests recorded for route %q at index [%d]", routeTests.Path, routeTests.RouteIndex)
+		if routeTests.Title != "" {
+			fmt.Fprintf(builder, " (%s)", routeTests.Title)
+		}
+		builder.WriteString("\n")
+		if err := encoder.Encode(routeTests.Tests); err != nil {
+			return fmt.Errorf("failed to marshal recorded tests: %w", err)
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
@@ -6,6 +6,8 @@ require (
 	github.com/google/cel-go v0.21.0
 	github.com/urfave/cli/v3 v3.0.0-alpha9.1
 	github.com/yuin/goldmark v1.7.8
+	golang.org/x/crypto v0.31.0
+	golang.org/x/text v0.21.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/slightly-inconvenient/murl/internal/clientip"
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Identity is an authenticated user and the groups it belongs to.
+type Identity struct {
+	User   string
+	Groups []string
+}
+
+// InGroup reports whether the identity belongs to the group.
+func (s Identity) InGroup(group string) bool {
+	return slices.Contains(s.Groups, group)
+}
+
+type kind int
+
+const (
+	kindBasic kind = iota
+	kindBearer
+	kindHeader
+)
+
+// Authenticator authenticates requests with a single configured method.
+type Authenticator struct {
+	kind         kind
+	realm        string
+	passwords    map[string]string
+	tokens       map[[sha256.Size]byte]string
+	userHeader   string
+	groupsHeader string
+	groups       map[string][]string
+	clientIP     clientip.Resolver
+}
+
+// NewAuthenticators parses the named authentication providers.
+// Identity headers are only trusted from peers the client address resolver considers trusted proxies.
+func NewAuthenticators(providers map[string]config.AuthProvider, clientIP clientip.Resolver) (map[string]*Authenticator, error) {
+	result := make(map[string]*Authenticator, len(providers))
+	for name, provider := range providers {
+		authenticator, err := newAuthenticator(provider, clientIP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse authentication provider %q: %w", name, err)
+		}
+		result[name] = authenticator
+	}
+
+	return result, nil
+}
+
+func newAuthenticator(provider config.AuthProvider, clientIP clientip.Resolver) (*Authenticator, error) {
+	configured := 0
+	for _, value := range []string{provider.Basic.HtpasswdFile, provider.Bearer.TokensFile, provider.Header.User} {
+		if value != "" {
+			configured++
+		}
+	}
+	if configured != 1 {
+		return nil, fmt.Errorf("exactly one of basic htpasswd file, bearer tokens file or header user must be set")
+	}
+
+	result := &Authenticator{
+		groups:   map[string][]string{},
+		clientIP: clientIP,
+	}
+	for group, users := range provider.Groups {
+		for _, user := range users {
+			result.groups[user] = append(result.groups[user], group)
+		}
+	}
+	for user := range result.groups {
+		slices.Sort(result.groups[user])
+	}
+
+	switch {
+	case provider.Basic.HtpasswdFile != "":
+		passwords, err := readHtpasswd(provider.Basic.HtpasswdFile)
+		if err != nil {
+			return nil, err
+		}
+		result.kind = kindBasic
+		result.passwords = passwords
+		result.realm = provider.Basic.Realm
+		if result.realm == "" {
+			result.realm = "murl"
+		}
+	case provider.Bearer.TokensFile != "":
+		tokens, err := readTokens(provider.Bearer.TokensFile)
+		if err != nil {
+			return nil, err
+		}
+		result.kind = kindBearer
+		result.tokens = tokens
+	default:
+		if len(provider.Groups) > 0 {
+			return nil, fmt.Errorf("groups are not supported with header authentication, use the groups header instead")
+		}
+		result.kind = kindHeader
+		result.userHeader = provider.Header.User
+		result.groupsHeader = provider.Header.Groups
+	}
+
+	return result, nil
+}
+
+// Authenticate returns the identity of the request. Returns false if the request is not authenticated.
+func (s *Authenticator) Authenticate(r *http.Request) (Identity, bool) {
+	switch s.kind {
+	case kindBasic:
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			return Identity{}, false
+		}
+		hash, ok := s.passwords[user]
+		if !ok || !verifyPassword(hash, password) {
+			return Identity{}, false
+		}
+		return Identity{User: user, Groups: s.groups[user]}, true
+	case kindBearer:
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return Identity{}, false
+		}
+		user, ok := s.tokens[sha256.Sum256([]byte(token))]
+		if !ok {
+			return Identity{}, false
+		}
+		return Identity{User: user, Groups: s.groups[user]}, true
+	default:
+		if !s.clientIP.TrustedPeer(r) {
+			return Identity{}, false
+		}
+		user := r.Header.Get(s.userHeader)
+		if user == "" {
+			return Identity{}, false
+		}
+		identity := Identity{User: user}
+		if s.groupsHeader != "" {
+			for _, group := range strings.Split(r.Header.Get(s.groupsHeader), ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					identity.Groups = append(identity.Groups, group)
+				}
+			}
+		}
+		return identity, true
+	}
+}
+
+// Challenge responds to an unauthenticated request.
+func (s *Authenticator) Challenge(w http.ResponseWriter) {
+	switch s.kind {
+	case kindBasic:
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", s.realm))
+	case kindBearer:
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+
+	http.Error(w, "authentication required", http.StatusUnauthorized)
+}
+
+func readHtpasswd(path string) (map[string]string, error) {
+	result := map[string]string{}
+	err := readLines(path, func(line string) error {
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" || hash == "" {
+			return fmt.Errorf("expected user:hash")
+		}
+		if !strings.HasPrefix(hash, "$2") && !strings.HasPrefix(hash, "{SHA}") {
+			return fmt.Errorf("unsupported hash for user %q (supported are bcrypt and SHA1)", user)
+		}
+		result[user] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file %s: %w", path, err)
+	}
+
+	return result, nil
+}
+
+func readTokens(path string) (map[[sha256.Size]byte]string, error) {
+	result := map[[sha256.Size]byte]string{}
+	err := readLines(path, func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("expected token and identity separated by whitespace")
+		}
+		result[sha256.Sum256([]byte(fields[0]))] = fields[1]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokens file %s: %w", path, err)
+	}
+
+	return result, nil
+}
+
+// readLines calls parse for each non-empty line not starting with a # comment.
+func readLines(path string, parse func(line string) error) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := parse(line); err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func verifyPassword(hash string, password string) bool {
+	if encoded, ok := strings.CutPrefix(hash, "{SHA}"); ok {
+		sum := sha1.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(encoded), []byte(base64.StdEncoding.EncodeToString(sum[:]))) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
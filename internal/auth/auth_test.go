@@ -0,0 +1,186 @@
+package auth_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/slightly-inconvenient/murl/internal/auth"
+	"github.com/slightly-inconvenient/murl/internal/clientip"
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	tempFile := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(tempFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	return tempFile
+}
+
+func TestAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	clientIP, err := clientip.NewResolver([]string{"10.0.0.1"}, "")
+	if err != nil {
+		t.Fatalf("failed to create client address resolver: %v", err)
+	}
+
+	authenticators, err := auth.NewAuthenticators(map[string]config.AuthProvider{
+		"basic": {
+			Basic: config.AuthBasic{
+				HtpasswdFile: writeTempFile(t, "# comment\nalice:$2a$04$qQKfdBvQ/cHDxNWz9X5preDwMEHdSCC6sUN6Si5yhPfSKWMzVsRsW\nbob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"),
+			},
+			Groups: map[string][]string{"admins": {"alice"}},
+		},
+		"bearer": {
+			Bearer: config.AuthBearer{
+				TokensFile: writeTempFile(t, "token-1 ci-bot\n"),
+			},
+		},
+		"header": {
+			Header: config.AuthHeader{
+				User:   "X-Forwarded-User",
+				Groups: "X-Forwarded-Groups",
+			},
+		},
+	}, clientIP)
+	if err != nil {
+		t.Fatalf("failed to create authenticators: %v", err)
+	}
+
+	tests := []struct {
+		description      string
+		authenticator    string
+		remoteAddr       string
+		headers          map[string]string
+		basicAuth        []string
+		expectedIdentity *auth.Identity
+	}{
+		{
+			description:      "basic auth with bcrypt hash",
+			authenticator:    "basic",
+			basicAuth:        []string{"alice", "secret"},
+			expectedIdentity: &auth.Identity{User: "alice", Groups: []string{"admins"}},
+		},
+		{
+			description:      "basic auth with sha1 hash",
+			authenticator:    "basic",
+			basicAuth:        []string{"bob", "secret"},
+			expectedIdentity: &auth.Identity{User: "bob"},
+		},
+		{
+			description:   "basic auth with wrong password",
+			authenticator: "basic",
+			basicAuth:     []string{"alice", "wrong"},
+		},
+		{
+			description:      "bearer token",
+			authenticator:    "bearer",
+			headers:          map[string]string{"Authorization": "Bearer token-1"},
+			expectedIdentity: &auth.Identity{User: "ci-bot"},
+		},
+		{
+			description:   "unknown bearer token",
+			authenticator: "bearer",
+			headers:       map[string]string{"Authorization": "Bearer token-2"},
+		},
+		{
+			description:      "identity header from trusted proxy",
+			authenticator:    "header",
+			remoteAddr:       "10.0.0.1:1234",
+			headers:          map[string]string{"X-Forwarded-User": "carol", "X-Forwarded-Groups": "devs, admins"},
+			expectedIdentity: &auth.Identity{User: "carol", Groups: []string{"devs", "admins"}},
+		},
+		{
+			description:   "identity header from untrusted peer",
+			authenticator: "header",
+			remoteAddr:    "10.0.0.2:1234",
+			headers:       map[string]string{"X-Forwarded-User": "carol"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.remoteAddr != "" {
+				req.RemoteAddr = test.remoteAddr
+			}
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			if test.basicAuth != nil {
+				req.SetBasicAuth(test.basicAuth[0], test.basicAuth[1])
+			}
+
+			identity, ok := authenticators[test.authenticator].Authenticate(req)
+			if test.expectedIdentity == nil {
+				if ok {
+					t.Fatalf("expected request to not be authenticated but got %v", identity)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("expected request to be authenticated")
+			}
+			if identity.User != test.expectedIdentity.User || !slices.Equal(identity.Groups, test.expectedIdentity.Groups) {
+				t.Fatalf("expected identity %v but got %v", *test.expectedIdentity, identity)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Failures(t *testing.T) {
+	t.Parallel()
+	htpasswdFile := writeTempFile(t, "alice:plain\n")
+	tokensFile := writeTempFile(t, "token-only\n")
+
+	tests := []struct {
+		description   string
+		provider      config.AuthProvider
+		expectedError error
+	}{
+		{
+			description:   "fails without a method",
+			provider:      config.AuthProvider{},
+			expectedError: errors.New("failed to parse authentication provider \"test\": exactly one of basic htpasswd file, bearer tokens file or header user must be set"),
+		},
+		{
+			description: "fails with unsupported htpasswd hash",
+			provider: config.AuthProvider{
+				Basic: config.AuthBasic{HtpasswdFile: htpasswdFile},
+			},
+			expectedError: fmt.Errorf("failed to parse authentication provider \"test\": failed to read htpasswd file %s: line 1: unsupported hash for user \"alice\" (supported are bcrypt and SHA1)", htpasswdFile),
+		},
+		{
+			description: "fails with malformed tokens file",
+			provider: config.AuthProvider{
+				Bearer: config.AuthBearer{TokensFile: tokensFile},
+			},
+			expectedError: fmt.Errorf("failed to parse authentication provider \"test\": failed to read tokens file %s: line 1: expected token and identity separated by whitespace", tokensFile),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := auth.NewAuthenticators(map[string]config.AuthProvider{"test": test.provider}, clientip.Resolver{})
+			if err == nil {
+				t.Fatalf("expected create authenticators to fail but got nil")
+			}
+
+			if err.Error() != test.expectedError.Error() {
+				t.Fatalf("expected error %q but got %q", test.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+const (
+	HeaderXForwardedFor = "x-forwarded-for"
+	HeaderForwarded     = "forwarded"
+)
+
+// Headers are the supported forwarding headers.
+var Headers = []string{HeaderXForwardedFor, HeaderForwarded}
+
+// Resolver resolves the client address of requests.
+// Only the forwarding header set by the trusted proxies is considered and only when the immediate peer is a trusted proxy.
+type Resolver struct {
+	trustedProxies []netip.Prefix
+	header         string
+}
+
+// NewResolver creates a resolver trusting the given proxy addresses or CIDR ranges to set the given forwarding header.
+// The header defaults to X-Forwarded-For.
+func NewResolver(trustedProxies []string, header string) (Resolver, error) {
+	prefixes, err := ParsePrefixes(trustedProxies)
+	if err != nil {
+		return Resolver{}, fmt.Errorf("failed to parse trusted proxies: %w", err)
+	}
+
+	header = strings.ToLower(header)
+	if header == "" {
+		header = HeaderXForwardedFor
+	}
+	if !slices.Contains(Headers, header) {
+		return Resolver{}, fmt.Errorf("unsupported forwarded header %q (supported are %s)", header, strings.Join(Headers, ", "))
+	}
+
+	return Resolver{trustedProxies: prefixes, header: header}, nil
+}
+
+// ParsePrefixes parses a list of addresses or CIDR ranges. Addresses are treated as single address ranges.
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	result := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q: %w", value, err)
+			}
+			result = append(result, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", value, err)
+		}
+		addr = addr.Unmap()
+		result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return result, nil
+}
+
+// Contains reports whether the address is in any of the prefixes.
+func Contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TrustedPeer reports whether the immediate peer of the request is a trusted proxy.
+func (s Resolver) TrustedPeer(r *http.Request) bool {
+	peer := ParseAddr(r.RemoteAddr)
+	return peer.IsValid() && Contains(s.trustedProxies, peer)
+}
+
+// Resolve returns the client address of the request.
+// The zero address is returned if the request remote address can not be parsed.
+func (s Resolver) Resolve(r *http.Request) netip.Addr {
+	peer := ParseAddr(r.RemoteAddr)
+	if !peer.IsValid() || !Contains(s.trustedProxies, peer) {
+		return peer
+	}
+
+	forwarded := forwardedFor(r.Header, s.header)
+	for idx := len(forwarded) - 1; idx >= 0; idx-- {
+		addr := ParseAddr(forwarded[idx])
+		if !addr.IsValid() {
+			return peer
+		}
+		if !Contains(s.trustedProxies, addr) {
+			return addr
+		}
+		peer = addr
+	}
+
+	return peer
+}
+
+// forwardedFor returns the client addresses appended by proxies to the forwarding header in order from the original client to the last proxy.
+func forwardedFor(header http.Header, name string) []string {
+	result := []string{}
+	if name == HeaderForwarded {
+		for _, value := range header.Values("Forwarded") {
+			for _, element := range strings.Split(value, ",") {
+				result = append(result, forwardedElementFor(element))
+			}
+		}
+		return result
+	}
+
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(value, ",") {
+			result = append(result, strings.TrimSpace(entry))
+		}
+	}
+
+	return result
+}
+
+// forwardedElementFor returns the for parameter of a Forwarded header element as defined in RFC 7239.
+// Obfuscated and unknown identifiers are returned as is and fail to parse as an address.
+func forwardedElementFor(element string) string {
+	for _, pair := range strings.Split(element, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(key, "for") {
+			return strings.Trim(value, `"`)
+		}
+	}
+
+	return ""
+}
+
+// ParseAddr parses an address with or without a port. The zero address is returned if the value can not be parsed.
+func ParseAddr(value string) netip.Addr {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return addr.Unmap()
+}
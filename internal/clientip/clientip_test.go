@@ -0,0 +1,124 @@
+package clientip_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slightly-inconvenient/murl/internal/clientip"
+)
+
+func TestResolver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description  string
+		header       string
+		remoteAddr   string
+		forwardedFor string
+		forwarded    string
+		expectedAddr string
+	}{
+		{
+			description:  "uses remote address without proxy",
+			remoteAddr:   "203.0.113.1:1234",
+			expectedAddr: "203.0.113.1",
+		},
+		{
+			description:  "ignores forwarded header from untrusted peer",
+			remoteAddr:   "203.0.113.1:1234",
+			forwardedFor: "198.51.100.1",
+			expectedAddr: "203.0.113.1",
+		},
+		{
+			description:  "uses forwarded header from trusted peer",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: "198.51.100.1",
+			expectedAddr: "198.51.100.1",
+		},
+		{
+			description:  "skips trusted proxies in forwarded chain",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: "203.0.113.7, 198.51.100.1, 192.168.1.1",
+			expectedAddr: "198.51.100.1",
+		},
+		{
+			description:  "falls back to last trusted proxy with malformed forwarded header",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: "garbage",
+			expectedAddr: "10.1.2.3",
+		},
+		{
+			description:  "ignores spoofed standard forwarded header next to proxy appended X-Forwarded-For",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: "198.51.100.1",
+			forwarded:    "for=10.0.0.1",
+			expectedAddr: "198.51.100.1",
+		},
+		{
+			description:  "uses standard forwarded header from trusted peer",
+			header:       clientip.HeaderForwarded,
+			remoteAddr:   "10.1.2.3:1234",
+			forwarded:    `for=203.0.113.7;proto=https, for="[2001:db8::1]:4711", for=192.168.1.1`,
+			expectedAddr: "2001:db8::1",
+		},
+		{
+			description:  "ignores spoofed X-Forwarded-For next to proxy appended standard forwarded header",
+			header:       clientip.HeaderForwarded,
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: "10.0.0.1",
+			forwarded:    "for=203.0.113.7",
+			expectedAddr: "203.0.113.7",
+		},
+		{
+			description:  "falls back to last trusted proxy with obfuscated forwarded identifier",
+			header:       clientip.HeaderForwarded,
+			remoteAddr:   "10.1.2.3:1234",
+			forwarded:    "for=_hidden",
+			expectedAddr: "10.1.2.3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+			if test.forwarded != "" {
+				req.Header.Set("Forwarded", test.forwarded)
+			}
+
+			resolver, err := clientip.NewResolver([]string{"10.0.0.0/8", "192.168.1.1"}, test.header)
+			if err != nil {
+				t.Fatalf("failed to create resolver: %v", err)
+			}
+
+			addr := resolver.Resolve(req)
+			if addr.String() != test.expectedAddr {
+				t.Fatalf("expected client address %s but got %s", test.expectedAddr, addr)
+			}
+		})
+	}
+
+	t.Run("fails with invalid trusted proxy", func(t *testing.T) {
+		t.Parallel()
+		_, err := clientip.NewResolver([]string{"10.0.0.0/33"}, "")
+		expectedError := errors.New("failed to parse trusted proxies: invalid CIDR range \"10.0.0.0/33\": netip.ParsePrefix(\"10.0.0.0/33\"): prefix length out of range")
+		if err == nil || err.Error() != expectedError.Error() {
+			t.Fatalf("expected error %q but got %v", expectedError, err)
+		}
+	})
+
+	t.Run("fails with unsupported forwarded header", func(t *testing.T) {
+		t.Parallel()
+		_, err := clientip.NewResolver(nil, "x-real-ip")
+		expectedError := errors.New("unsupported forwarded header \"x-real-ip\" (supported are x-forwarded-for, forwarded)")
+		if err == nil || err.Error() != expectedError.Error() {
+			t.Fatalf("expected error %q but got %v", expectedError, err)
+		}
+	})
+}
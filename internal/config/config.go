@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"gopkg.in/yaml.v3"
 )
@@ -25,9 +26,37 @@ type RouteCheck struct {
 	Error string `yaml:"error" json:"error"`
 }
 
+type RouteRedirectTarget struct {
+	// Name identifies the target in sticky assignments and access logs.
+	Name string `yaml:"name" json:"name"`
+
+	// URL is the template to build the redirect URL from.
+	URL string `yaml:"url" json:"url"`
+
+	// Weight is the relative share of requests redirected to the target.
+	Weight int `yaml:"weight" json:"weight"`
+}
+
+type RouteRedirectSticky struct {
+	// Cookie is the name of a cookie to persist the assigned target in.
+	Cookie string `yaml:"cookie" json:"cookie"`
+
+	// Header is the name of a request header whose value is hashed to assign the target.
+	Header string `yaml:"header" json:"header"`
+
+	// Param is the name of a route param whose value is hashed to assign the target.
+	Param string `yaml:"param" json:"param"`
+}
+
 type RouteRedirect struct {
 	// URL is the template to build the redirect URL from.
 	URL string `yaml:"url" json:"url"`
+
+	// Targets are weighted redirect targets to split requests between. Mutually exclusive with URL.
+	Targets []RouteRedirectTarget `yaml:"targets" json:"targets"`
+
+	// Sticky optionally keeps a client assigned to the same target across requests.
+	Sticky RouteRedirectSticky `yaml:"sticky" json:"sticky"`
 }
 
 type RouteDocumentation struct {
@@ -38,20 +67,120 @@ type RouteDocumentation struct {
 	Description string `yaml:"description" json:"description"`
 }
 
-type RouteTestRequest struct {
-	// Environment defines the environment variables and their values
-	Environment map[string]string `yaml:"environment" json:"environment"`
+type RouteDeprecation struct {
+	// Path is the route path or alias that is deprecated.
+	Path string `yaml:"path" json:"path"`
 
-	// Headers defines the headers that should be sent with the request
-	Headers map[string]string `yaml:"headers" json:"headers"`
+	// Message is a human-readable explanation of the deprecation, e.g. which path to use instead.
+	Message string `yaml:"message" json:"message"`
+
+	// Sunset is an optional RFC 3339 timestamp after which the path stops redirecting.
+	Sunset string `yaml:"sunset" json:"sunset"`
+
+	// Interstitial shows a page with the deprecation message and a link to the destination instead of redirecting.
+	Interstitial bool `yaml:"interstitial" json:"interstitial"`
+}
+
+type RouteInactive struct {
+	// Status is the response status code for requests outside of the active window.
+	// Either 404 (default) or 410.
+	Status int `yaml:"status" json:"status"`
+
+	// URL is an optional fallback url to redirect to instead of responding with an error status.
+	URL string `yaml:"url" json:"url"`
+}
+
+type RateLimitKey struct {
+	// Header is the name of a request header whose value the limit is keyed by.
+	Header string `yaml:"header" json:"header"`
+
+	// Param is the name of a route param whose value the limit is keyed by. Only supported on routes.
+	Param string `yaml:"param" json:"param"`
+}
+
+type RateLimit struct {
+	// Requests is the number of requests allowed per period. If omitted, requests are not limited.
+	Requests int `yaml:"requests" json:"requests"`
+
+	// Period is the duration the requests are allowed in, e.g. "1m".
+	Period string `yaml:"period" json:"period"`
+
+	// Burst is the number of requests allowed at once. Defaults to requests.
+	Burst int `yaml:"burst" json:"burst"`
+
+	// Key defines what the limit is keyed by. Defaults to the client IP.
+	Key RateLimitKey `yaml:"key" json:"key"`
+
+	// MaxKeys bounds the number of keys tracked at once. Least recently used keys are evicted first.
+	MaxKeys int `yaml:"max_keys" json:"max_keys"`
+}
+
+type IPAccessDenied struct {
+	// Message is the response body for denied requests. Defaults to "forbidden".
+	Message string `yaml:"message" json:"message"`
+
+	// URL is an optional url to redirect denied requests to instead of responding with 403 Forbidden.
+	URL string `yaml:"url" json:"url"`
+}
+
+type IPAccess struct {
+	// Allow lists the client addresses or CIDR ranges allowed. If omitted, all addresses not denied are allowed.
+	Allow []string `yaml:"allow" json:"allow"`
+
+	// Deny lists the client addresses or CIDR ranges denied. Takes precedence over allow.
+	Deny []string `yaml:"deny" json:"deny"`
+
+	// Denied defines the response for denied requests.
+	Denied IPAccessDenied `yaml:"denied" json:"denied"`
+}
+
+type RouteTestRequest struct {
+	// Method defines the request method. Defaults to GET.
+	Method string `yaml:"method,omitempty" json:"method"`
 
 	// URL defines the request url to send
 	URL string `yaml:"url" json:"url"`
+
+	// Headers defines the headers that should be sent with the request
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers"`
+
+	// Cookies defines the cookies that should be sent with the request
+	Cookies map[string]string `yaml:"cookies,omitempty" json:"cookies"`
+
+	// Environment defines the environment variables and their values
+	Environment map[string]string `yaml:"environment,omitempty" json:"environment"`
+
+	// Time defines the RFC 3339 timestamp the request is evaluated at. Defaults to the current time.
+	Time string `yaml:"time,omitempty" json:"time"`
+
+	// RemoteAddr defines the simulated address of the immediate peer, with or without port. Defaults to 192.0.2.1:1234.
+	RemoteAddr string `yaml:"remote_addr,omitempty" json:"remote_addr"`
+
+	// Bucket pins the weighted redirect bucket in the range [0, sum of target weights) the request is assigned to.
+	Bucket *int `yaml:"bucket,omitempty" json:"bucket"`
 }
 
 type RouteTestResponse struct {
+	// Status defines the expected response status code. Defaults to 307 unless no redirect is expected.
+	Status int `yaml:"status,omitempty" json:"status"`
+
 	// URL defines the expected response url
-	URL string `yaml:"url" json:"url"`
+	URL string `yaml:"url,omitempty" json:"url"`
+
+	// NoRedirect expects the response not to be a redirect, e.g. a failing check.
+	NoRedirect bool `yaml:"no_redirect,omitempty" json:"no_redirect"`
+
+	// Body defines a substring the response body is expected to contain.
+	Body string `yaml:"body,omitempty" json:"body"`
+
+	// BodyRegex defines a regular expression the response body is expected to match.
+	BodyRegex string `yaml:"body_regex,omitempty" json:"body_regex"`
+
+	// Headers defines the expected response header values.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers"`
+
+	// RateLimitedAfter defines the number of times the request is expected to succeed before being rate limited.
+	RateLimitedAfter int `yaml:"rate_limited_after,omitempty" json:"rate_limited_after"`
 }
 
 type RouteTest struct {
@@ -60,6 +189,18 @@ type RouteTest struct {
 
 	// Response defines the expected response data
 	Response RouteTestResponse `yaml:"response" json:"response"`
+
+	// Rows parameterizes the test. The test is expanded into a test per row with the string values of the request
+	// and response executed as Go text/template templates given the row variables as input, e.g. {{.id}}.
+	Rows []map[string]string `yaml:"rows,omitempty" json:"rows"`
+}
+
+type RouteTestFixtures struct {
+	// Environment defines environment variables set for every test request. Values of the test request take precedence.
+	Environment map[string]string `yaml:"environment" json:"environment"`
+
+	// Headers defines headers sent with every test request. Values of the test request take precedence.
+	Headers map[string]string `yaml:"headers" json:"headers"`
 }
 
 type Route struct {
@@ -69,23 +210,121 @@ type Route struct {
 	// Aliases are additional absolute paths to match against.
 	Aliases []string `yaml:"aliases" json:"aliases"`
 
+	// Deprecations marks the path or any of the aliases as deprecated.
+	Deprecations []RouteDeprecation `yaml:"deprecations" json:"deprecations"`
+
+	// ActiveFrom is an optional RFC 3339 timestamp before which the route is inactive.
+	ActiveFrom string `yaml:"active_from" json:"active_from"`
+
+	// ActiveUntil is an optional RFC 3339 timestamp after which the route is inactive.
+	ActiveUntil string `yaml:"active_until" json:"active_until"`
+
+	// Inactive defines the response for requests outside of the active window.
+	Inactive RouteInactive `yaml:"inactive" json:"inactive"`
+
 	// Documentation defines the human-readable documentation attributes for the route.
 	Documentation RouteDocumentation `yaml:"documentation" json:"documentation"`
 
 	// Environment defines the environment variables the route may consume.
 	Environment RouteEnvironment `yaml:"environment" json:"environment"`
 
+	// Languages are the BCP 47 language tags the route has localized destinations for.
+	// The first language is the default if none of the requested languages match.
+	Languages []string `yaml:"languages" json:"languages"`
+
+	// Authentication is the name of the authentication provider requests to the route must authenticate with.
+	Authentication string `yaml:"authentication" json:"authentication"`
+
+	// IPAccess restricts the client addresses allowed to use the route in addition to the server wide restrictions.
+	IPAccess IPAccess `yaml:"ip_access" json:"ip_access"`
+
 	// Params are the template parameters to extract and build the redirect URL from.
 	Params map[string]string `yaml:"params" json:"params"`
 
 	// Checks are the conditions to evaluate before redirecting.
 	Checks []RouteCheck `yaml:"checks" json:"checks"`
 
+	// RateLimit limits the requests to the route in addition to the server wide rate limit.
+	RateLimit RateLimit `yaml:"rate_limit" json:"rate_limit"`
+
 	// Redirect is the redirect configuration.
 	Redirect RouteRedirect `yaml:"redirect" json:"redirect"`
 
 	// Tests defines the valid route resulting redirect tests
 	Tests []RouteTest `yaml:"tests" json:"tests"`
+
+	// TestFixtures defines request values shared by the tests of the route. They take precedence over the fixtures for all routes.
+	TestFixtures RouteTestFixtures `yaml:"test_fixtures" json:"test_fixtures"`
+
+	// Lint configures the lint rules for the route.
+	Lint Lint `yaml:"lint" json:"lint"`
+}
+
+type Lint struct {
+	// Disable are the names of the lint rules not to report.
+	Disable []string `yaml:"disable" json:"disable"`
+}
+
+type RouteGroup struct {
+	// Prefix is the absolute path prefix of the paths, aliases and deprecations of the member routes.
+	Prefix string `yaml:"prefix" json:"prefix"`
+
+	// Documentation defines the human-readable documentation section the member routes are listed under.
+	Documentation RouteDocumentation `yaml:"documentation" json:"documentation"`
+
+	// Environment defines the environment variables all member routes may consume.
+	Environment RouteEnvironment `yaml:"environment" json:"environment"`
+
+	// Params are the template parameters shared by all member routes. Member routes may not redefine them.
+	Params map[string]string `yaml:"params" json:"params"`
+
+	// Checks are the conditions to evaluate before the checks of the member routes.
+	Checks []RouteCheck `yaml:"checks" json:"checks"`
+
+	// Authentication is the name of the authentication provider requests to the member routes must authenticate with.
+	// Member routes may not require a different provider.
+	Authentication string `yaml:"authentication" json:"authentication"`
+
+	// IPAccess restricts the client addresses allowed to use the member routes in addition to the server wide and route restrictions.
+	IPAccess IPAccess `yaml:"ip_access" json:"ip_access"`
+
+	// Routes are the member routes of the group.
+	Routes []Route `yaml:"routes" json:"routes"`
+}
+
+type AuthBasic struct {
+	// HtpasswdFile is the path to an htpasswd file with bcrypt or SHA1 hashed passwords.
+	HtpasswdFile string `yaml:"htpasswd_file" json:"htpasswd_file"`
+
+	// Realm is the realm sent in the basic auth challenge.
+	Realm string `yaml:"realm" json:"realm"`
+}
+
+type AuthBearer struct {
+	// TokensFile is the path to a file with a token and the identity it authenticates as separated by whitespace per line.
+	TokensFile string `yaml:"tokens_file" json:"tokens_file"`
+}
+
+type AuthHeader struct {
+	// User is the name of the header the trusted proxy sets the authenticated user in.
+	User string `yaml:"user" json:"user"`
+
+	// Groups is the name of the header the trusted proxy sets the comma separated groups of the user in.
+	Groups string `yaml:"groups" json:"groups"`
+}
+
+type AuthProvider struct {
+	// Basic authenticates requests with htpasswd style basic auth.
+	Basic AuthBasic `yaml:"basic" json:"basic"`
+
+	// Bearer authenticates requests with static bearer tokens.
+	Bearer AuthBearer `yaml:"bearer" json:"bearer"`
+
+	// Header authenticates requests with identity headers set by a trusted proxy.
+	Header AuthHeader `yaml:"header" json:"header"`
+
+	// Groups maps group names to the users belonging to them for basic and bearer authentication.
+	Groups map[string][]string `yaml:"groups" json:"groups"`
 }
 
 type ServerTLSConfig struct {
@@ -94,6 +333,12 @@ type ServerTLSConfig struct {
 
 	// Key is the path to the server TLS key file.
 	Key string `yaml:"key" json:"key"`
+
+	// ClientCA is the path to a CA bundle file to verify client certificates against. If omitted, client certificates are not requested.
+	ClientCA string `yaml:"client_ca" json:"client_ca"`
+
+	// ClientAuth defines whether a verified client certificate is "require"d (default) or "optional".
+	ClientAuth string `yaml:"client_auth" json:"client_auth"`
 }
 
 type ServerTemplatesConfig struct {
@@ -108,6 +353,12 @@ type ServerDocumentationConfig struct {
 	// Path defines the route to serve the documentation from.
 	Path string `yaml:"path" json:"path"`
 
+	// HideProtected hides routes requiring authentication from the documentation of unauthenticated requests.
+	HideProtected bool `yaml:"hide_protected" json:"hide_protected"`
+
+	// Authentication is the name of the authentication provider used to authenticate documentation requests when hiding protected routes.
+	Authentication string `yaml:"authentication" json:"authentication"`
+
 	// Templates defines the server documentation templates.
 	Templates ServerTemplatesConfig `yaml:"templates" json:"templates"`
 }
@@ -119,6 +370,18 @@ type Server struct {
 	// TLS is the server TLS configuration. If omitted, the server will serve over plain HTTP.
 	TLS ServerTLSConfig `yaml:"tls" json:"tls"`
 
+	// TrustedProxies are the proxy addresses or CIDR ranges whose forwarding headers are trusted to resolve client addresses.
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
+
+	// ForwardedHeader is the forwarding header the trusted proxies set, either x-forwarded-for or forwarded. Defaults to x-forwarded-for.
+	ForwardedHeader string `yaml:"forwarded_header" json:"forwarded_header"`
+
+	// IPAccess restricts the client addresses allowed to use any route.
+	IPAccess IPAccess `yaml:"ip_access" json:"ip_access"`
+
+	// RateLimit limits the requests to all routes.
+	RateLimit RateLimit `yaml:"rate_limit" json:"rate_limit"`
+
 	// Documentation is the server documentation rendering configuration.
 	Documentation ServerDocumentationConfig `yaml:"documentation" json:"documentation"`
 }
@@ -127,8 +390,46 @@ type Config struct {
 	// Server defines the instance wide serving configuration.
 	Server Server `yaml:"server" json:"server"`
 
+	// Vars are constants available to all route templates and checks as vars.x.
+	Vars map[string]string `yaml:"vars" json:"vars"`
+
+	// Templates are named templates every route template is parsed alongside, e.g. {{template "name" .}}.
+	Templates map[string]string `yaml:"templates" json:"templates"`
+
+	// Authentication defines the named authentication providers routes may require.
+	Authentication map[string]AuthProvider `yaml:"authentication" json:"authentication"`
+
 	// Routes defines the routes to expose as redirects.
 	Routes []Route `yaml:"routes" json:"routes"`
+
+	// Groups defines routes sharing a path prefix, environment, params and checks.
+	// Member routes are indexed after the routes above in the order of the groups.
+	Groups []RouteGroup `yaml:"groups" json:"groups"`
+
+	// Lint configures the lint rules for all routes.
+	Lint Lint `yaml:"lint" json:"lint"`
+
+	// TestFixtures defines request values shared by the tests of all routes.
+	TestFixtures RouteTestFixtures `yaml:"test_fixtures" json:"test_fixtures"`
+}
+
+// ReferencedFiles returns the sorted paths of the files the configuration references, e.g. htpasswd and TLS certificate files.
+func (s Config) ReferencedFiles() []string {
+	files := []string{
+		s.Server.TLS.Cert,
+		s.Server.TLS.Key,
+		s.Server.TLS.ClientCA,
+	}
+	for _, provider := range s.Authentication {
+		files = append(files, provider.Basic.HtpasswdFile, provider.Bearer.TokensFile)
+	}
+
+	files = slices.DeleteFunc(files, func(file string) bool {
+		return file == ""
+	})
+	slices.Sort(files)
+
+	return slices.Compact(files)
 }
 
 func ParseConfigFile(path string) (Config, error) {
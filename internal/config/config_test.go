@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/slightly-inconvenient/murl/internal/config"
@@ -194,3 +195,27 @@ routes:
 		})
 	}
 }
+
+func TestReferencedFiles(t *testing.T) {
+	t.Parallel()
+
+	conf := config.Config{
+		Server: config.Server{
+			TLS: config.ServerTLSConfig{Cert: "tls.crt", Key: "tls.key"},
+			// Documentation templates are inline templates rather than files
+			Documentation: config.ServerDocumentationConfig{
+				Templates: config.ServerTemplatesConfig{Page: "<html>{{.Content}}</html>"},
+			},
+		},
+		Authentication: map[string]config.AuthProvider{
+			"admins": {Basic: config.AuthBasic{HtpasswdFile: "htpasswd"}},
+			"ci":     {Bearer: config.AuthBearer{TokensFile: "tokens"}},
+			"other":  {Basic: config.AuthBasic{HtpasswdFile: "htpasswd"}},
+		},
+	}
+
+	expected := []string{"htpasswd", "tls.crt", "tls.key", "tokens"}
+	if files := conf.ReferencedFiles(); !slices.Equal(files, expected) {
+		t.Fatalf("expected referenced files %q but got %q", expected, files)
+	}
+}
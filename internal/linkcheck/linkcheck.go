@@ -0,0 +1,254 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/route"
+)
+
+const (
+	defaultConcurrency  = 4
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRedirects = 10
+)
+
+// Options configure how destinations are checked.
+type Options struct {
+	// Concurrency is the maximum number of destinations checked at the same time. Defaults to 4.
+	Concurrency int
+
+	// Timeout is the maximum time to check a single destination including redirects. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// AllowedHosts are the hosts destinations may be checked on. A leading "*." matches any subdomain.
+	// Destinations on other hosts are skipped. All hosts are allowed if empty.
+	AllowedHosts []string
+
+	// Client sends the requests. Defaults to a client with the default transport.
+	Client *http.Client
+}
+
+// Result is the result of checking a single destination.
+type Result struct {
+	Destination route.Destination
+
+	// Status is the status code of the final response. Zero if no response was received.
+	Status int
+
+	// Skipped is the reason the destination was not checked, if any.
+	Skipped string
+
+	// Error is the reason the destination is broken, if any.
+	Error string
+}
+
+// OK reports whether the destination was checked and is not broken.
+func (s Result) OK() bool {
+	return s.Skipped == "" && s.Error == ""
+}
+
+var errRedirectLoop = errors.New("redirect loop")
+
+// Check requests every destination with HEAD, falling back to GET if the destination does not support HEAD, and returns the result of each in order.
+// Redirects are followed as long as they stay on allowed hosts. Error statuses, DNS failures, timeouts, redirect loops
+// and redirects to hosts not allowed are reported as errors.
+// Every distinct url is only requested once.
+func Check(ctx context.Context, destinations []route.Destination, options Options) []Result {
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	client := &http.Client{}
+	if options.Client != nil {
+		client = &http.Client{Transport: options.Client.Transport, Jar: options.Client.Jar}
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return checkRedirect(req, via, options.AllowedHosts)
+	}
+
+	type outcome struct {
+		status int
+		err    string
+	}
+	outcomes := map[string]*outcome{}
+	results := make([]Result, len(destinations))
+	for idx, destination := range destinations {
+		results[idx].Destination = destination
+		if reason := skipReason(destination.URL, options.AllowedHosts); reason != "" {
+			results[idx].Skipped = reason
+			continue
+		}
+		if _, ok := outcomes[destination.URL]; !ok {
+			outcomes[destination.URL] = &outcome{}
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	semaphore := make(chan struct{}, options.Concurrency)
+	for link, result := range outcomes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				result.err = ctx.Err().Error()
+				return
+			}
+
+			status, err := check(ctx, client, link, options.Timeout)
+			result.status = status
+			if err != nil {
+				result.err = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for idx := range results {
+		if result, ok := outcomes[results[idx].Destination.URL]; ok && results[idx].Skipped == "" {
+			results[idx].Status = result.status
+			results[idx].Error = result.err
+		}
+	}
+
+	return results
+}
+
+func check(ctx context.Context, client *http.Client, link string, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	status, err := request(ctx, client, http.MethodHead, link)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = request(ctx, client, http.MethodGet, link)
+	}
+	if err != nil {
+		return status, describe(err)
+	}
+	if status >= 400 {
+		return status, fmt.Errorf("status %d %s", status, http.StatusText(status))
+	}
+
+	return status, nil
+}
+
+func request(ctx context.Context, client *http.Client, method string, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	return resp.StatusCode, nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request, allowedHosts []string) error {
+	if reason := skipReason(req.URL.String(), allowedHosts); reason != "" {
+		return fmt.Errorf("redirect to %s not followed: %s", req.URL, reason)
+	}
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return fmt.Errorf("%w at %s", errRedirectLoop, req.URL)
+		}
+	}
+	if len(via) >= defaultMaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", defaultMaxRedirects)
+	}
+
+	return nil
+}
+
+// describe returns a short description of the request error without the request method and url.
+func describe(err error) error {
+	dnsErr := &net.DNSError{}
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("dns lookup of %q failed: %s", dnsErr.Name, dnsErr.Err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return errors.New("timed out")
+	}
+
+	urlErr := &url.Error{}
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+
+	return err
+}
+
+func skipReason(link string, allowedHosts []string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "not an absolute http or https url"
+	}
+	if len(allowedHosts) == 0 {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("host %q is not allowed", host)
+}
+
+// Failed returns the number of broken destinations.
+func Failed(results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Write writes the result of every destination grouped by route followed by a summary.
+func Write(w io.Writer, results []Result) error {
+	builder := &strings.Builder{}
+	skipped := 0
+	for _, result := range results {
+		destination := result.Destination
+		name := fmt.Sprintf("%s of route %q at index [%d] %s", destination.Source, destination.Path, destination.RouteIndex, destination.URL)
+		switch {
+		case result.Skipped != "":
+			skipped++
+			fmt.Fprintf(builder, "SKIP %s: %s\n", name, result.Skipped)
+		case result.Error != "":
+			fmt.Fprintf(builder, "FAIL %s: %s\n", name, result.Error)
+		default:
+			fmt.Fprintf(builder, "OK   %s (%d)\n", name, result.Status)
+		}
+	}
+
+	failed := Failed(results)
+	fmt.Fprintf(builder, "%d links, %d ok, %d failed, %d skipped\n", len(results), len(results)-failed-skipped, failed, skipped)
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
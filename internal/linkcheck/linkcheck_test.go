@@ -0,0 +1,144 @@
+package linkcheck_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/linkcheck"
+	"github.com/slightly-inconvenient/murl/internal/route"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	mu := sync.Mutex{}
+	requests := map[string]int{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.Method+" "+r.URL.Path]++
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusBadGateway)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop-a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop-b", http.StatusFound)
+	})
+	mux.HandleFunc("/loop-b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop-a", http.StatusFound)
+	})
+	mux.HandleFunc("/offsite", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://other.example/landing", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	// Resolving any host other than the test server fails without network access
+	client := server.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		if host != "127.0.0.1" {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	client.Transport = transport
+
+	destination := func(source string, url string) route.Destination {
+		return route.Destination{RouteIndex: 0, Path: "/link", Source: source, URL: url}
+	}
+	destinations := []route.Destination{
+		destination("test [0]", server.URL+"/ok"),
+		destination("test [1]", server.URL+"/ok"),
+		destination("test [2]", server.URL+"/missing"),
+		destination("test [3]", server.URL+"/broken"),
+		destination("test [4]", server.URL+"/get-only"),
+		destination("test [5]", server.URL+"/moved"),
+		destination("test [6]", server.URL+"/loop-a"),
+		destination("test [7]", server.URL+"/slow"),
+		destination("test [8]", "http://vendor.invalid/path"),
+		destination("test [9]", "https://other.example/path"),
+		destination("test [10]", server.URL+"/offsite"),
+		destination("redirect url", "/relative"),
+	}
+
+	results := linkcheck.Check(context.Background(), destinations, linkcheck.Options{
+		Concurrency:  2,
+		Timeout:      200 * time.Millisecond,
+		AllowedHosts: []string{"127.0.0.1", "*.invalid"},
+		Client:       client,
+	})
+
+	expected := []linkcheck.Result{
+		{Destination: destinations[0], Status: http.StatusOK},
+		{Destination: destinations[1], Status: http.StatusOK},
+		{Destination: destinations[2], Status: http.StatusNotFound, Error: "status 404 Not Found"},
+		{Destination: destinations[3], Status: http.StatusBadGateway, Error: "status 502 Bad Gateway"},
+		{Destination: destinations[4], Status: http.StatusOK},
+		{Destination: destinations[5], Status: http.StatusOK},
+		{Destination: destinations[6], Error: "redirect loop at " + server.URL + "/loop-a"},
+		{Destination: destinations[7], Error: "timed out"},
+		{Destination: destinations[8], Error: `dns lookup of "vendor.invalid" failed: no such host`},
+		{Destination: destinations[9], Skipped: `host "other.example" is not allowed`},
+		{Destination: destinations[10], Error: `redirect to https://other.example/landing not followed: host "other.example" is not allowed`},
+		{Destination: destinations[11], Skipped: "not an absolute http or https url"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected results\n%+v\nbut got\n%+v", expected, results)
+	}
+
+	if requests["HEAD /ok"] != 2 {
+		t.Fatalf("expected the destination and the redirect to it to be requested once each with HEAD but got %d requests", requests["HEAD /ok"])
+	}
+
+	if failed := linkcheck.Failed(results); failed != 6 {
+		t.Fatalf("expected 6 failed links but got %d", failed)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	results := []linkcheck.Result{
+		{Destination: route.Destination{RouteIndex: 0, Path: "/a", Source: "test [0]", URL: "https://example.com/a"}, Status: http.StatusOK},
+		{Destination: route.Destination{RouteIndex: 1, Path: "/b", Source: "redirect url", URL: "https://example.com/b"}, Status: http.StatusNotFound, Error: "status 404 Not Found"},
+		{Destination: route.Destination{RouteIndex: 2, Path: "/c", Source: `redirect target "new"`, URL: "https://other.example/c"}, Skipped: `host "other.example" is not allowed`},
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := linkcheck.Write(buffer, results); err != nil {
+		t.Fatalf("expected write to succeed but got error: %v", err)
+	}
+
+	expectedOutput := `OK   test [0] of route "/a" at index [0] https://example.com/a (200)
+FAIL redirect url of route "/b" at index [1] https://example.com/b: status 404 Not Found
+SKIP redirect target "new" of route "/c" at index [2] https://other.example/c: host "other.example" is not allowed
+3 links, 1 ok, 1 failed, 1 skipped
+`
+	if buffer.String() != expectedOutput {
+		t.Fatalf("expected output\n%s\nbut got\n%s", expectedOutput, buffer.String())
+	}
+}
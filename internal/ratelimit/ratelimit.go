@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultMaxKeys is the default number of keys tracked by a limiter.
+const DefaultMaxKeys = 10000
+
+type bucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter is a token bucket rate limiter keyed by e.g. client address.
+// The number of tracked keys is bounded, evicting the least recently used key when the bound is reached.
+type Limiter struct {
+	rate    float64
+	burst   float64
+	maxKeys int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+}
+
+// New creates a limiter refilling rate tokens per second up to burst tokens, tracking at most maxKeys keys.
+func New(rate float64, burst int, maxKeys int) *Limiter {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element, maxKeys),
+		lru:     list.New(),
+	}
+}
+
+// Allow consumes a token for the key at the given time.
+// If no token is available it returns false and the duration until the next token is available.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.buckets[key]
+	if !ok {
+		if l.lru.Len() >= l.maxKeys {
+			oldest := l.lru.Back()
+			l.lru.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*bucket).key)
+		}
+
+		element = l.lru.PushFront(&bucket{key: key, tokens: l.burst, updated: now})
+		l.buckets[key] = element
+	} else {
+		l.lru.MoveToFront(element)
+	}
+
+	b := element.Value.(*bucket)
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.updated = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Len returns the number of tracked keys.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lru.Len()
+}
@@ -0,0 +1,58 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("limits after burst and refills over time", func(t *testing.T) {
+		t.Parallel()
+		limiter := ratelimit.New(1, 2, 0)
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		for idx := range 2 {
+			if ok, _ := limiter.Allow("a", now); !ok {
+				t.Fatalf("expected request %d to be allowed", idx)
+			}
+		}
+
+		ok, wait := limiter.Allow("a", now)
+		if ok {
+			t.Fatalf("expected request to be limited")
+		}
+		if wait != time.Second {
+			t.Fatalf("expected wait of 1s but got %s", wait)
+		}
+
+		if ok, _ := limiter.Allow("b", now); !ok {
+			t.Fatalf("expected request for other key to be allowed")
+		}
+
+		if ok, _ := limiter.Allow("a", now.Add(time.Second)); !ok {
+			t.Fatalf("expected request to be allowed after refill")
+		}
+	})
+
+	t.Run("bounds tracked keys", func(t *testing.T) {
+		t.Parallel()
+		limiter := ratelimit.New(1, 1, 2)
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		for _, key := range []string{"a", "b", "c"} {
+			limiter.Allow(key, now)
+		}
+
+		if limiter.Len() != 2 {
+			t.Fatalf("expected 2 tracked keys but got %d", limiter.Len())
+		}
+
+		if ok, _ := limiter.Allow("a", now); !ok {
+			t.Fatalf("expected evicted key to start with a full bucket")
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package route
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/slightly-inconvenient/murl/internal/auth"
+)
+
+// authParam is the reserved param the authenticated identity is exposed as to checks and templates.
+const authParam = "auth"
+
+func parseRouteAuthentication(name string, params map[string]string, authenticators map[string]*auth.Authenticator) (*auth.Authenticator, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	authenticator, ok := authenticators[name]
+	if !ok {
+		return nil, fmt.Errorf("authentication provider %q is not defined", name)
+	}
+
+	if _, ok := params[authParam]; ok {
+		return nil, fmt.Errorf("param %q is reserved for the authenticated identity", authParam)
+	}
+
+	return authenticator, nil
+}
+
+// Authenticator returns the named authentication provider of the configuration the routes were created from.
+func Authenticator(routes []Route, name string) (*auth.Authenticator, bool) {
+	if len(routes) == 0 {
+		return nil, false
+	}
+
+	authenticator, ok := routes[0].authenticators[name]
+	return authenticator, ok
+}
+
+// authCelOptions returns the CEL environment options exposing the authenticated identity as auth.user and auth.groups.
+func authCelOptions(authenticator *auth.Authenticator) []cel.EnvOption {
+	if authenticator == nil {
+		return nil
+	}
+
+	return []cel.EnvOption{
+		cel.Variable(authParam, cel.MapType(cel.StringType, cel.DynType)),
+	}
+}
+
+func authParamValue(identity auth.Identity) map[string]any {
+	groups := identity.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return map[string]any{
+		"user":   identity.User,
+		"groups": groups,
+	}
+}
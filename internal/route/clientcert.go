@@ -0,0 +1,91 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/cel-go/cel"
+)
+
+// clientCertParam is the reserved param the verified client certificate is exposed as to checks and templates.
+const clientCertParam = "tls"
+
+// clientCertificate is the verified client certificate of a mutual TLS request.
+type clientCertificate struct {
+	Verified      bool
+	Subject       string
+	CommonName    string
+	Organizations []string
+	SANs          []string
+}
+
+// parseRouteClientCert reports whether routes are served with client certificate verification enabled.
+func parseRouteClientCert(clientCA string, params map[string]string) (bool, error) {
+	if clientCA == "" {
+		return false, nil
+	}
+
+	if _, ok := params[clientCertParam]; ok {
+		return false, fmt.Errorf("param %q is reserved for the client certificate", clientCertParam)
+	}
+
+	return true, nil
+}
+
+// requestClientCertificate returns the verified client certificate of the request.
+// Unverified certificates are ignored and result in the zero value.
+func requestClientCertificate(r *http.Request) clientCertificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return clientCertificate{}
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	result := clientCertificate{
+		Verified:      true,
+		Subject:       cert.Subject.String(),
+		CommonName:    cert.Subject.CommonName,
+		Organizations: append([]string{}, cert.Subject.Organization...),
+		SANs:          []string{},
+	}
+	result.SANs = append(result.SANs, cert.DNSNames...)
+	result.SANs = append(result.SANs, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		result.SANs = append(result.SANs, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		result.SANs = append(result.SANs, uri.String())
+	}
+
+	return result
+}
+
+// clientCertCelOptions returns the CEL environment options exposing the client certificate as
+// tls.verified, tls.subject, tls.common_name, tls.organizations and tls.sans.
+func clientCertCelOptions(enabled bool) []cel.EnvOption {
+	if !enabled {
+		return nil
+	}
+
+	return []cel.EnvOption{
+		cel.Variable(clientCertParam, cel.MapType(cel.StringType, cel.DynType)),
+	}
+}
+
+func clientCertParamValue(cert clientCertificate) map[string]any {
+	organizations := cert.Organizations
+	if organizations == nil {
+		organizations = []string{}
+	}
+	sans := cert.SANs
+	if sans == nil {
+		sans = []string{}
+	}
+
+	return map[string]any{
+		"verified":      cert.Verified,
+		"subject":       cert.Subject,
+		"common_name":   cert.CommonName,
+		"organizations": organizations,
+		"sans":          sans,
+	}
+}
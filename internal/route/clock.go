@@ -0,0 +1,23 @@
+package route
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type timeContextKey struct{}
+
+// withTime pins the time requests are evaluated at. Used by route tests to verify time dependent behaviour.
+func withTime(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, timeContextKey{}, now)
+}
+
+// requestTime returns the time the request is evaluated at.
+func requestTime(r *http.Request) time.Time {
+	if now, ok := r.Context().Value(timeContextKey{}).(time.Time); ok {
+		return now
+	}
+
+	return time.Now()
+}
@@ -2,16 +2,23 @@ package route
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
+	"regexp"
+	"slices"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/google/cel-go/cel"
+	"github.com/slightly-inconvenient/murl/internal/auth"
+	"github.com/slightly-inconvenient/murl/internal/clientip"
 	"github.com/slightly-inconvenient/murl/internal/config"
 )
 
 type RouteEnvironment struct {
 	allowedEnvVariables map[string]bool
+	snapshot            Environment
 }
 
 type RouteCheck struct {
@@ -20,48 +27,142 @@ type RouteCheck struct {
 	// If the expression evaluates to anything else, the check fails.
 	expr cel.Program
 
+	// source is the expression the program is compiled from.
+	source string
+
 	// Error is the error message to return if the check fails.
 	error *template.Template
 }
 
-type RouteRedirect struct {
-	url *template.Template
-}
-
 type RouteTestRequest struct {
-	environment map[string]string
+	environment Environment
+	method      string
 	headers     map[string]string
+	cookies     map[string]string
 	url         string
+	time        time.Time
+	remoteAddr  string
+	bucket      *int
 }
 
 type RouteTestResponse struct {
-	status int
-	url    string
+	// status is the expected status code. Zero accepts any status.
+	status           int
+	url              string
+	noRedirect       bool
+	body             string
+	bodyRegex        *regexp.Regexp
+	headers          map[string]string
+	rateLimitedAfter int
 }
 
 type RouteTest struct {
+	// index is the index of the test configuration the test was parsed from.
+	index int
+
+	// row is the row of the parameterized test configuration the test was expanded from. Nil if the test is not parameterized.
+	row *TestRow
+
 	request  RouteTestRequest
 	response RouteTestResponse
 }
 
 type Route struct {
-	paths       []string
-	environment RouteEnvironment
-	params      map[string]*template.Template
-	checks      []RouteCheck
-	redirect    RouteRedirect
-	tests       []RouteTest
-	valid       bool
+	index         int
+	prefix        string
+	paths         []string
+	title         string
+	authenticator *auth.Authenticator
+	// authenticators are all authentication providers of the configuration, shared by the routes.
+	authenticators map[string]*auth.Authenticator
+	clientCert     bool
+	vars           map[string]string
+	templates      *RouteTemplates
+	celEnv         *cel.Env
+	deprecations   map[string]RouteDeprecation
+	window         RouteWindow
+	environment    RouteEnvironment
+	languages      RouteLanguages
+	params         map[string]*template.Template
+	clientIP       clientip.Resolver
+	ipAccess       []*RouteIPAccess
+	rateLimits     []*RouteRateLimit
+	checks         []RouteCheck
+	redirect       RouteRedirect
+	tests          []RouteTest
+	valid          bool
 }
 
-// NewRoutes parses the input routes and returns a validated route for each.
+// NewRoutes parses the routes of the input configuration and returns a validated route for each.
 // A validated route guarantees that all required fields are present and passed all static validation such as pre-compilation of templates.
-func NewRoutes(routes []config.Route) ([]Route, error) {
+// Each route resolves its allowlisted environment variables from the given environment snapshot.
+// The member routes of groups are expanded with ExpandGroup and follow the ungrouped routes.
+func NewRoutes(conf config.Config, env Environment) ([]Route, error) {
+	clientIP, err := clientip.NewResolver(conf.Server.TrustedProxies, conf.Server.ForwardedHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server client address configuration: %w", err)
+	}
+
+	authenticators, err := auth.NewAuthenticators(conf.Authentication, clientIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse authentication: %w", err)
+	}
+
+	serverIPAccess, err := parseIPAccess(conf.Server.IPAccess)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server ip access: %w", err)
+	}
+
+	serverRateLimit, err := parseRateLimit(conf.Server.RateLimit, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server rate limit: %w", err)
+	}
+
+	routes, err := expandRoutes(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIPAccess := make([]*RouteIPAccess, 0, len(conf.Groups))
+	for idx, group := range conf.Groups {
+		ipAccess, err := parseIPAccess(group.IPAccess)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ip access for group at index [%d]: %w", idx, err)
+		}
+		groupIPAccess = append(groupIPAccess, ipAccess)
+	}
+
+	templates, err := parseRouteTemplates(conf.Templates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	vars := maps.Clone(conf.Vars)
+	groups := routeGroups(conf)
+
 	result := make([]Route, 0, len(routes))
 
 	for idx, route := range routes {
+		prefix := ""
+		if group := groups[idx]; group >= 0 {
+			prefix = strings.TrimSuffix(conf.Groups[group].Prefix, "/")
+		}
+
 		resultRoute := Route{
-			valid: true,
+			index:          idx,
+			prefix:         prefix,
+			authenticators: authenticators,
+			clientIP:       clientIP,
+			templates:      templates,
+			valid:          true,
+		}
+		if serverIPAccess != nil {
+			resultRoute.ipAccess = append(resultRoute.ipAccess, serverIPAccess)
+		}
+		if group := groups[idx]; group >= 0 && groupIPAccess[group] != nil {
+			resultRoute.ipAccess = append(resultRoute.ipAccess, groupIPAccess[group])
+		}
+		if serverRateLimit != nil {
+			resultRoute.rateLimits = append(resultRoute.rateLimits, serverRateLimit)
 		}
 
 		paths, err := parseRoutePaths(route.Path, route.Aliases)
@@ -69,49 +170,114 @@ func NewRoutes(routes []config.Route) ([]Route, error) {
 			return nil, fmt.Errorf("failed to parse path or alias for route at index [%d]: %w", idx, err)
 		}
 		resultRoute.paths = paths
+		resultRoute.title = route.Documentation.Title
+
+		deprecations, err := parseRouteDeprecations(route.Deprecations, paths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deprecations for route at index [%d]: %w", idx, err)
+		}
+		resultRoute.deprecations = deprecations
+
+		window, err := parseRouteWindow(route.ActiveFrom, route.ActiveUntil, route.Inactive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse active window for route at index [%d]: %w", idx, err)
+		}
+		resultRoute.window = window
 
-		params, err := parseRouteParams(route.Params)
+		ipAccess, err := parseIPAccess(route.IPAccess)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ip access for route at index [%d]: %w", idx, err)
+		}
+		if ipAccess != nil {
+			resultRoute.ipAccess = append(resultRoute.ipAccess, ipAccess)
+		}
+
+		params, err := parseRouteParams(route.Params, templates)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse params for route at index [%d]: %w", idx, err)
 		}
 		resultRoute.params = params
 
 		resultRoute.environment.allowedEnvVariables = parseRouteEnvAllowlist(route.Environment.Allowlist)
+		resultRoute.environment.snapshot = env.filter(resultRoute.environment.allowedEnvVariables)
+
+		languages, err := parseRouteLanguages(route.Languages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse languages for route at index [%d]: %w", idx, err)
+		}
+		resultRoute.languages = languages
 
-		celEnv, err := parseRouteCheckCelEnv(route.Params)
+		authenticator, err := parseRouteAuthentication(route.Authentication, route.Params, authenticators)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse authentication for route at index [%d]: %w", idx, err)
+		}
+		resultRoute.authenticator = authenticator
+
+		clientCert, err := parseRouteClientCert(conf.Server.TLS.ClientCA, route.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate for route at index [%d]: %w", idx, err)
+		}
+		resultRoute.clientCert = clientCert
+
+		routeVars, err := parseRouteVars(vars, route.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vars for route at index [%d]: %w", idx, err)
+		}
+		resultRoute.vars = routeVars
+
+		celEnv, err := parseRouteCheckCelEnv(route.Params, languages, authenticator, clientCert, routeVars)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create new CEL environment for route at index [%d]: %w", idx, err)
 		}
+		resultRoute.celEnv = celEnv
+
+		rateLimit, err := parseRateLimit(route.RateLimit, route.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rate limit for route at index [%d]: %w", idx, err)
+		}
+		if rateLimit != nil {
+			resultRoute.rateLimits = append(resultRoute.rateLimits, rateLimit)
+		}
 
 		for idx := range route.Checks {
 			expr, err := parseRouteCheckExpr(route.Checks[idx].Expr, celEnv)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse check expression for route at index [%d]: %w", idx, err)
 			}
-			tmpl, err := parseTemplate(route.Checks[idx].Error)
+			tmpl, err := parseTemplate(route.Checks[idx].Error, templates)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse check error template for route at index [%d]: %w", idx, err)
 			}
 
 			resultRoute.checks = append(resultRoute.checks, RouteCheck{
-				expr:  expr,
-				error: tmpl,
+				expr:   expr,
+				source: route.Checks[idx].Expr,
+				error:  tmpl,
 			})
 		}
 
 		for tidx, test := range route.Tests {
-			resultTest, err := parseTest(test)
+			resultTests, err := parseTest(test, conf.TestFixtures, route.TestFixtures)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse test [%d] for route at index [%d]: %w", tidx, idx, err)
 			}
-			resultRoute.tests = append(resultRoute.tests, resultTest)
+			for _, resultTest := range resultTests {
+				resultTest.index = tidx
+				resultRoute.tests = append(resultRoute.tests, resultTest)
+			}
 		}
 
-		parsedURL, err := parseTemplate(route.Redirect.URL)
-		resultRoute.redirect.url = parsedURL
+		redirect, err := parseRouteRedirect(route.Redirect, route.Params, templates)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse redirect url for route at index [%d]: %w", idx, err)
 		}
+		resultRoute.redirect = redirect
+
+		for _, test := range resultRoute.tests {
+			if test.request.bucket != nil && (*test.request.bucket < 0 || *test.request.bucket >= redirect.totalWeight) {
+				return nil, fmt.Errorf("%s for route at index [%d] bucket must be in range [0, %d)", test.name(), idx, redirect.totalWeight)
+			}
+		}
 
 		result = append(result, resultRoute)
 	}
@@ -132,10 +298,10 @@ func parseRoutePaths(path string, aliases []string) ([]string, error) {
 	return paths, nil
 }
 
-func parseRouteParams(params map[string]string) (map[string]*template.Template, error) {
+func parseRouteParams(params map[string]string, templates *RouteTemplates) (map[string]*template.Template, error) {
 	result := make(map[string]*template.Template, len(params))
 	for key, value := range params {
-		parsedTemplate, err := template.New("").Parse(value)
+		parsedTemplate, err := templates.parse(value)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse param template %q: %w", key, err)
 		}
@@ -154,11 +320,15 @@ func parseRouteEnvAllowlist(allowlist []string) map[string]bool {
 	return lookup
 }
 
-func parseRouteCheckCelEnv(params map[string]string) (*cel.Env, error) {
+func parseRouteCheckCelEnv(params map[string]string, languages RouteLanguages, authenticator *auth.Authenticator, clientCert bool, vars map[string]string) (*cel.Env, error) {
 	options := make([]cel.EnvOption, 0, len(params))
 	for key := range params {
 		options = append(options, cel.Variable(key, cel.StringType))
 	}
+	options = append(options, languages.celOptions()...)
+	options = append(options, authCelOptions(authenticator)...)
+	options = append(options, clientCertCelOptions(clientCert)...)
+	options = append(options, varsCelOptions(vars)...)
 
 	return cel.NewEnv(options...)
 }
@@ -181,12 +351,12 @@ func parseRouteCheckExpr(expr string, env *cel.Env) (cel.Program, error) {
 	return prg, nil
 }
 
-func parseTemplate(tmpl string) (*template.Template, error) {
+func parseTemplate(tmpl string, templates *RouteTemplates) (*template.Template, error) {
 	if tmpl == "" {
 		return nil, fmt.Errorf("missing template")
 	}
 
-	parsedTemplate, err := template.New("").Parse(tmpl)
+	parsedTemplate, err := templates.parse(tmpl)
 	if err != nil {
 		return nil, err
 	}
@@ -194,25 +364,198 @@ func parseTemplate(tmpl string) (*template.Template, error) {
 	return parsedTemplate, nil
 }
 
-func parseTest(test config.RouteTest) (RouteTest, error) {
+// parseTest returns the tests of the test configuration with the fixtures applied in order of precedence.
+// A parameterized test is expanded into a test per row.
+func parseTest(test config.RouteTest, fixtures ...config.RouteTestFixtures) ([]RouteTest, error) {
+	for _, fixture := range slices.Backward(fixtures) {
+		test.Request.Environment = mergeTestValues(fixture.Environment, test.Request.Environment)
+		test.Request.Headers = mergeTestValues(fixture.Headers, test.Request.Headers)
+	}
+
+	if test.Rows == nil {
+		result, err := parseTestCase(test)
+		if err != nil {
+			return nil, err
+		}
+		return []RouteTest{result}, nil
+	}
+	if len(test.Rows) == 0 {
+		return nil, fmt.Errorf("test rows must not be empty")
+	}
+
+	results := make([]RouteTest, 0, len(test.Rows))
+	for ridx, row := range test.Rows {
+		expanded, err := expandTestRow(test, row)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand row [%d]: %w", ridx, err)
+		}
+
+		result, err := parseTestCase(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse row [%d]: %w", ridx, err)
+		}
+		result.row = &TestRow{Index: ridx, Values: row}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// mergeTestValues returns the values with the fixture values not overridden added.
+func mergeTestValues(fixture map[string]string, values map[string]string) map[string]string {
+	if len(fixture) == 0 {
+		return values
+	}
+
+	result := maps.Clone(fixture)
+	maps.Copy(result, values)
+
+	return result
+}
+
+// expandTestRow returns the test with its string request and response values executed as templates given the row.
+func expandTestRow(test config.RouteTest, row map[string]string) (config.RouteTest, error) {
+	var err error
+	expand := func(value string) string {
+		if err != nil || !strings.Contains(value, "{{") {
+			return value
+		}
+
+		tmpl, parseErr := template.New("row").Option("missingkey=error").Parse(value)
+		if parseErr != nil {
+			err = fmt.Errorf("failed to parse %q: %w", value, parseErr)
+			return value
+		}
+
+		builder := &strings.Builder{}
+		if executeErr := tmpl.Execute(builder, row); executeErr != nil {
+			err = fmt.Errorf("failed to execute %q: %w", value, executeErr)
+			return value
+		}
+
+		return builder.String()
+	}
+	expandValues := func(values map[string]string) map[string]string {
+		if values == nil {
+			return nil
+		}
+
+		result := make(map[string]string, len(values))
+		for key, value := range values {
+			result[key] = expand(value)
+		}
+
+		return result
+	}
+
+	result := config.RouteTest{
+		Request: config.RouteTestRequest{
+			Environment: expandValues(test.Request.Environment),
+			Headers:     expandValues(test.Request.Headers),
+			Method:      expand(test.Request.Method),
+			Cookies:     expandValues(test.Request.Cookies),
+			URL:         expand(test.Request.URL),
+			Time:        expand(test.Request.Time),
+			RemoteAddr:  expand(test.Request.RemoteAddr),
+			Bucket:      test.Request.Bucket,
+		},
+		Response: config.RouteTestResponse{
+			Status:           test.Response.Status,
+			URL:              expand(test.Response.URL),
+			NoRedirect:       test.Response.NoRedirect,
+			Body:             expand(test.Response.Body),
+			BodyRegex:        expand(test.Response.BodyRegex),
+			Headers:          expandValues(test.Response.Headers),
+			RateLimitedAfter: test.Response.RateLimitedAfter,
+		},
+	}
+
+	return result, err
+}
+
+func parseTestCase(test config.RouteTest) (RouteTest, error) {
 	result := RouteTest{
 		request: RouteTestRequest{
 			url:         test.Request.URL,
+			method:      http.MethodGet,
 			headers:     test.Request.Headers,
-			environment: test.Request.Environment,
+			cookies:     test.Request.Cookies,
+			environment: NewEnvironment(test.Request.Environment),
+			remoteAddr:  test.Request.RemoteAddr,
+			bucket:      test.Request.Bucket,
 		},
 		response: RouteTestResponse{
-			status: http.StatusTemporaryRedirect,
-			url:    test.Response.URL,
+			status:           test.Response.Status,
+			url:              test.Response.URL,
+			noRedirect:       test.Response.NoRedirect,
+			body:             test.Response.Body,
+			headers:          test.Response.Headers,
+			rateLimitedAfter: test.Response.RateLimitedAfter,
 		},
 	}
 
 	if result.request.url == "" {
 		return RouteTest{}, fmt.Errorf("test request url is required but was missing")
 	}
-	if result.response.url == "" {
+	if test.Request.Method != "" {
+		method := strings.ToUpper(test.Request.Method)
+		if !slices.Contains(testRequestMethods, method) {
+			return RouteTest{}, fmt.Errorf("test request method %q is not supported", test.Request.Method)
+		}
+		result.request.method = method
+	}
+	if test.Request.Time != "" {
+		requestTime, err := time.Parse(time.RFC3339, test.Request.Time)
+		if err != nil {
+			return RouteTest{}, fmt.Errorf("failed to parse test request time: %w", err)
+		}
+		result.request.time = requestTime
+	}
+	if result.request.remoteAddr != "" && !clientip.ParseAddr(result.request.remoteAddr).IsValid() {
+		return RouteTest{}, fmt.Errorf("test request remote address %q is not a valid address", result.request.remoteAddr)
+	}
+
+	if result.response.status != 0 && (result.response.status < 100 || result.response.status > 599) {
+		return RouteTest{}, fmt.Errorf("test response status %d is not a valid status code", result.response.status)
+	}
+	if result.response.noRedirect {
+		if result.response.url != "" {
+			return RouteTest{}, fmt.Errorf("test response url and no redirect are mutually exclusive")
+		}
+		if isRedirectStatus(result.response.status) {
+			return RouteTest{}, fmt.Errorf("test response status %d is a redirect but no redirect is expected", result.response.status)
+		}
+	} else if result.response.status == 0 {
+		result.response.status = http.StatusTemporaryRedirect
+	}
+	if result.response.url == "" && isRedirectStatus(result.response.status) {
 		return RouteTest{}, fmt.Errorf("test response url is required but was missing")
 	}
+	if test.Response.BodyRegex != "" {
+		bodyRegex, err := regexp.Compile(test.Response.BodyRegex)
+		if err != nil {
+			return RouteTest{}, fmt.Errorf("failed to parse test response body regex: %w", err)
+		}
+		result.response.bodyRegex = bodyRegex
+	}
+	if result.response.rateLimitedAfter < 0 {
+		return RouteTest{}, fmt.Errorf("test response rate limited after must not be negative")
+	}
 
 	return result, nil
 }
+
+// testRequestMethods are the request methods route tests may send.
+var testRequestMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+func isRedirectStatus(status int) bool {
+	return status >= 300 && status < 400
+}
@@ -2,6 +2,7 @@ package route_test
 
 import (
 	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/slightly-inconvenient/murl/internal/config"
@@ -52,7 +53,7 @@ func buildTestRoute(transforms ...routeTestTransform) config.Route {
 func TestConfig_Success(t *testing.T) {
 	t.Parallel()
 	input := buildTestRoute()
-	routes, err := route.NewRoutes([]config.Route{input})
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{input}}, route.Environment{})
 	if err != nil {
 		t.Fatalf("expected create routes to succeed but got error: %s", err)
 	}
@@ -82,6 +83,194 @@ func TestConfig_Failures(t *testing.T) {
 			}),
 			expectedError: errors.New("failed to parse path or alias for route at index [0]: \"example2\" must be an absolute path (start with slash)"),
 		},
+		{
+			description: "fails with deprecation of unknown path",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Deprecations = []config.RouteDeprecation{{Path: "/unknown"}}
+			}),
+			expectedError: errors.New("failed to parse deprecations for route at index [0]: deprecated path \"/unknown\" is not the path or an alias of the route"),
+		},
+		{
+			description: "fails with invalid deprecation sunset",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Deprecations = []config.RouteDeprecation{{Path: "/example2/{rest}", Sunset: "2024-01-01"}}
+			}),
+			expectedError: errors.New("failed to parse deprecations for route at index [0]: failed to parse sunset for deprecated path \"/example2/{rest}\": parsing time \"2024-01-01\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"\" as \"T\""),
+		},
+		{
+			description: "fails with invalid active from",
+			route: buildTestRoute(func(route *config.Route) {
+				route.ActiveFrom = "tomorrow"
+			}),
+			expectedError: errors.New("failed to parse active window for route at index [0]: failed to parse active from: parsing time \"tomorrow\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"tomorrow\" as \"2006\""),
+		},
+		{
+			description: "fails with active from after active until",
+			route: buildTestRoute(func(route *config.Route) {
+				route.ActiveFrom = "2024-02-01T00:00:00Z"
+				route.ActiveUntil = "2024-01-01T00:00:00Z"
+			}),
+			expectedError: errors.New("failed to parse active window for route at index [0]: active from 2024-02-01T00:00:00Z must be before active until 2024-01-01T00:00:00Z"),
+		},
+		{
+			description: "fails with unsupported inactive status",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Inactive.Status = http.StatusOK
+			}),
+			expectedError: errors.New("failed to parse active window for route at index [0]: inactive status must be 404 or 410 but was 200"),
+		},
+		{
+			description: "fails with invalid ip access allow list",
+			route: buildTestRoute(func(route *config.Route) {
+				route.IPAccess.Allow = []string{"vpn"}
+			}),
+			expectedError: errors.New("failed to parse ip access for route at index [0]: failed to parse allow list: invalid address \"vpn\": ParseAddr(\"vpn\"): unable to parse IP"),
+		},
+		{
+			description: "fails with ip access denied response without lists",
+			route: buildTestRoute(func(route *config.Route) {
+				route.IPAccess.Denied.Message = "go away"
+			}),
+			expectedError: errors.New("failed to parse ip access for route at index [0]: denied response requires an allow or deny list"),
+		},
+		{
+			description: "fails with unsupported test request method",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc", Method: "FETCH"},
+					Response: config.RouteTestResponse{URL: "https://example.com"},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: test request method \"FETCH\" is not supported"),
+		},
+		{
+			description: "fails with test response url and no redirect",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc"},
+					Response: config.RouteTestResponse{URL: "https://example.com", NoRedirect: true},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: test response url and no redirect are mutually exclusive"),
+		},
+		{
+			description: "fails with test response redirect status and no redirect",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc"},
+					Response: config.RouteTestResponse{Status: http.StatusFound, NoRedirect: true},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: test response status 302 is a redirect but no redirect is expected"),
+		},
+		{
+			description: "fails with invalid test response status",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc"},
+					Response: config.RouteTestResponse{Status: 42},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: test response status 42 is not a valid status code"),
+		},
+		{
+			description: "fails with invalid test response body regex",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc"},
+					Response: config.RouteTestResponse{NoRedirect: true, BodyRegex: "("},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: failed to parse test response body regex: error parsing regexp: missing closing ): `(`"),
+		},
+		{
+			description: "fails with invalid test request remote address",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc", RemoteAddr: "localhost:1234"},
+					Response: config.RouteTestResponse{URL: "https://example.com"},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: test request remote address \"localhost:1234\" is not a valid address"),
+		},
+		{
+			description: "fails with invalid test request time",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc", Time: "now"},
+					Response: config.RouteTestResponse{URL: "https://example.com"},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: failed to parse test request time: parsing time \"now\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"now\" as \"2006\""),
+		},
+		{
+			description: "fails with empty test rows",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc"},
+					Response: config.RouteTestResponse{URL: "https://example.com"},
+					Rows:     []map[string]string{},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: test rows must not be empty"),
+		},
+		{
+			description: "fails with test row missing a variable",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/{{.id}}"},
+					Response: config.RouteTestResponse{URL: "https://example.com/{{.id}}"},
+					Rows:     []map[string]string{{"id": "1"}, {"ID": "2"}},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: failed to expand row [1]: failed to execute \"/example/{{.id}}\": template: row:1:11: executing \"row\" at <.id>: map has no entry for key \"id\""),
+		},
+		{
+			description: "fails with invalid test row value",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc", Time: "{{.time}}"},
+					Response: config.RouteTestResponse{URL: "https://example.com"},
+					Rows:     []map[string]string{{"time": "now"}},
+				}}
+			}),
+			expectedError: errors.New("failed to parse test [0] for route at index [0]: failed to parse row [0]: failed to parse test request time: parsing time \"now\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"now\" as \"2006\""),
+		},
+		{
+			description: "fails with invalid supported language",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Languages = []string{"en", "not a language"}
+			}),
+			expectedError: errors.New("failed to parse languages for route at index [0]: failed to parse supported language \"not a language\": language: tag is not well-formed"),
+		},
+		{
+			description: "fails with language check without supported languages",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Checks[0].Expr = `language(host) == "en"`
+			}),
+			expectedError: errors.New("failed to parse check expression for route at index [0]: ERROR: <input>:1:9: undeclared reference to 'language' (in container '')\n | language(host) == \"en\"\n | ........^"),
+		},
+		{
+			description: "fails with invalid rate limit period",
+			route: buildTestRoute(func(route *config.Route) {
+				route.RateLimit = config.RateLimit{Requests: 10, Period: "often"}
+			}),
+			expectedError: errors.New("failed to parse rate limit for route at index [0]: failed to parse period: time: invalid duration \"often\""),
+		},
+		{
+			description: "fails with unknown rate limit key param",
+			route: buildTestRoute(func(route *config.Route) {
+				route.RateLimit = config.RateLimit{Requests: 10, Period: "1m", Key: config.RateLimitKey{Param: "user"}}
+			}),
+			expectedError: errors.New("failed to parse rate limit for route at index [0]: key param \"user\" is not defined in params"),
+		},
+		{
+			description: "fails with unknown authentication provider",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Authentication = "sso"
+			}),
+			expectedError: errors.New("failed to parse authentication for route at index [0]: authentication provider \"sso\" is not defined"),
+		},
 		{
 			description: "fails with bad params input",
 			route: buildTestRoute(func(route *config.Route) {
@@ -124,6 +313,89 @@ func TestConfig_Failures(t *testing.T) {
 			}),
 			expectedError: errors.New("failed to parse redirect url for route at index [0]: template: :1: unexpected \"{\" in command"),
 		},
+		{
+			description: "fails with redirect url and targets",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Redirect.Targets = []config.RouteRedirectTarget{{Name: "a", URL: "https://a.example.com", Weight: 1}}
+			}),
+			expectedError: errors.New("failed to parse redirect url for route at index [0]: redirect url and targets are mutually exclusive"),
+		},
+		{
+			description: "fails with unnamed redirect target",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Redirect = config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{{URL: "https://a.example.com", Weight: 1}},
+				}
+			}),
+			expectedError: errors.New("failed to parse redirect url for route at index [0]: redirect target [0] is missing a name"),
+		},
+		{
+			description: "fails with duplicate redirect target names",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Redirect = config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{
+						{Name: "a", URL: "https://a.example.com", Weight: 1},
+						{Name: "a", URL: "https://b.example.com", Weight: 1},
+					},
+				}
+			}),
+			expectedError: errors.New("failed to parse redirect url for route at index [0]: redirect target name \"a\" is defined more than once"),
+		},
+		{
+			description: "fails with zero total redirect target weight",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Redirect = config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{{Name: "a", URL: "https://a.example.com"}},
+				}
+			}),
+			expectedError: errors.New("failed to parse redirect url for route at index [0]: redirect targets must have a positive total weight"),
+		},
+		{
+			description: "fails with bad redirect target url template",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Redirect = config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{{Name: "a", URL: "{{{}}", Weight: 1}},
+				}
+			}),
+			expectedError: errors.New("failed to parse redirect url for route at index [0]: failed to parse redirect target \"a\": template: :1: unexpected \"{\" in command"),
+		},
+		{
+			description: "fails with multiple sticky sources",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Redirect = config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{{Name: "a", URL: "https://a.example.com", Weight: 1}},
+					Sticky:  config.RouteRedirectSticky{Cookie: "variant", Header: "x-user"},
+				}
+			}),
+			expectedError: errors.New("failed to parse redirect url for route at index [0]: only one of sticky cookie, header or param may be set"),
+		},
+		{
+			description: "fails with unknown sticky param",
+			route: buildTestRoute(func(route *config.Route) {
+				route.Redirect = config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{{Name: "a", URL: "https://a.example.com", Weight: 1}},
+					Sticky:  config.RouteRedirectSticky{Param: "user"},
+				}
+			}),
+			expectedError: errors.New("failed to parse redirect url for route at index [0]: sticky param \"user\" is not defined in params"),
+		},
+		{
+			description: "fails with test bucket out of range",
+			route: buildTestRoute(func(route *config.Route) {
+				bucket := 10
+				route.Redirect = config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{
+						{Name: "a", URL: "https://a.example.com", Weight: 5},
+						{Name: "b", URL: "https://b.example.com", Weight: 5},
+					},
+				}
+				route.Tests = []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/example/abc", Bucket: &bucket},
+					Response: config.RouteTestResponse{URL: "https://a.example.com"},
+				}}
+			}),
+			expectedError: errors.New("test [0] for route at index [0] bucket must be in range [0, 10)"),
+		},
 		{
 			description: "fails with missing redirect url template",
 			route: buildTestRoute(func(route *config.Route) {
@@ -133,10 +405,190 @@ func TestConfig_Failures(t *testing.T) {
 		},
 	}
 
+	t.Run("fails with server rate limit keyed by param", func(t *testing.T) {
+		t.Parallel()
+		_, err := route.NewRoutes(config.Config{
+			Server: config.Server{
+				RateLimit: config.RateLimit{Requests: 10, Period: "1m", Key: config.RateLimitKey{Param: "user"}},
+			},
+		}, route.Environment{})
+		expectedError := errors.New("failed to parse server rate limit: key param \"user\" is not defined in params")
+		if err == nil || err.Error() != expectedError.Error() {
+			t.Fatalf("expected error %q but got %v", expectedError, err)
+		}
+	})
+
+	t.Run("fails with client certificate param overridden", func(t *testing.T) {
+		t.Parallel()
+		_, err := route.NewRoutes(config.Config{
+			Server: config.Server{
+				TLS: config.ServerTLSConfig{ClientCA: "/path/to/ca"},
+			},
+			Routes: []config.Route{buildTestRoute(func(route *config.Route) {
+				route.Params = map[string]string{"tls": "on"}
+			})},
+		}, route.Environment{})
+		expectedError := errors.New("failed to parse client certificate for route at index [0]: param \"tls\" is reserved for the client certificate")
+		if err == nil || err.Error() != expectedError.Error() {
+			t.Fatalf("expected error %q but got %v", expectedError, err)
+		}
+	})
+
+	t.Run("fails with invalid server ip access deny list", func(t *testing.T) {
+		t.Parallel()
+		_, err := route.NewRoutes(config.Config{
+			Server: config.Server{
+				IPAccess: config.IPAccess{Deny: []string{"10.0.0.0/40"}},
+			},
+		}, route.Environment{})
+		expectedError := errors.New("failed to parse server ip access: failed to parse deny list: invalid CIDR range \"10.0.0.0/40\": netip.ParsePrefix(\"10.0.0.0/40\"): prefix length out of range")
+		if err == nil || err.Error() != expectedError.Error() {
+			t.Fatalf("expected error %q but got %v", expectedError, err)
+		}
+	})
+
+	t.Run("fails with invalid group", func(t *testing.T) {
+		t.Parallel()
+		groupTests := []struct {
+			description   string
+			group         config.RouteGroup
+			expectedError error
+		}{
+			{
+				description:   "relative prefix",
+				group:         config.RouteGroup{Prefix: "jira"},
+				expectedError: errors.New("failed to expand group at index [0]: prefix \"jira\" must be an absolute path (start with slash)"),
+			},
+			{
+				description: "relative member path",
+				group: config.RouteGroup{
+					Prefix: "/jira",
+					Routes: []config.Route{buildTestRoute(func(route *config.Route) {
+						route.Aliases = []string{"browse"}
+					})},
+				},
+				expectedError: errors.New("failed to expand group at index [0]: path or alias \"browse\" of route at index [0] must be an absolute path (start with slash)"),
+			},
+			{
+				description: "redefined group param",
+				group: config.RouteGroup{
+					Prefix: "/jira",
+					Params: map[string]string{"path": "shared"},
+					Routes: []config.Route{buildTestRoute()},
+				},
+				expectedError: errors.New("failed to expand group at index [0]: param \"path\" of route at index [0] is already defined by the group"),
+			},
+			{
+				description: "different member authentication",
+				group: config.RouteGroup{
+					Prefix:         "/jira",
+					Authentication: "sso",
+					Routes: []config.Route{buildTestRoute(func(route *config.Route) {
+						route.Authentication = "tokens"
+					})},
+				},
+				expectedError: errors.New("failed to expand group at index [0]: authentication \"tokens\" of route at index [0] differs from the group authentication \"sso\""),
+			},
+			{
+				description: "invalid group ip access",
+				group: config.RouteGroup{
+					Prefix:   "/jira",
+					IPAccess: config.IPAccess{Allow: []string{"10.0.0.0/40"}},
+					Routes:   []config.Route{buildTestRoute()},
+				},
+				expectedError: errors.New("failed to parse ip access for group at index [0]: failed to parse allow list: invalid CIDR range \"10.0.0.0/40\": netip.ParsePrefix(\"10.0.0.0/40\"): prefix length out of range"),
+			},
+			{
+				description: "member route indexed after ungrouped routes",
+				group: config.RouteGroup{
+					Prefix: "/jira",
+					Routes: []config.Route{buildTestRoute(func(route *config.Route) {
+						route.Redirect.URL = ""
+					})},
+				},
+				expectedError: errors.New("failed to parse redirect url for route at index [1]: missing template"),
+			},
+		}
+
+		for _, test := range groupTests {
+			_, err := route.NewRoutes(config.Config{
+				Routes: []config.Route{buildTestRoute()},
+				Groups: []config.RouteGroup{test.group},
+			}, route.Environment{})
+			if err == nil || err.Error() != test.expectedError.Error() {
+				t.Fatalf("%s: expected error %q but got %v", test.description, test.expectedError, err)
+			}
+		}
+	})
+
+	t.Run("fails with invalid templates or vars", func(t *testing.T) {
+		t.Parallel()
+		templateTests := []struct {
+			description   string
+			config        config.Config
+			expectedError error
+		}{
+			{
+				description: "named template parse error",
+				config: config.Config{
+					Templates: map[string]string{"base": "{{.host"},
+				},
+				expectedError: errors.New("failed to parse templates: failed to parse template \"base\": template: base:1: unclosed action"),
+			},
+			{
+				description: "named template referencing undefined template",
+				config: config.Config{
+					Templates: map[string]string{"base": `{{template "host" .}}`},
+				},
+				expectedError: errors.New("failed to parse templates: failed to parse template \"base\": template \"host\" is not defined"),
+			},
+			{
+				description: "route template referencing undefined template",
+				config: config.Config{
+					Templates: map[string]string{"base": "https://example.com"},
+					Routes: []config.Route{buildTestRoute(func(route *config.Route) {
+						route.Redirect.URL = `{{if .path}}{{template "jiraBase" .}}{{end}}`
+					})},
+				},
+				expectedError: errors.New("failed to parse redirect url for route at index [0]: template \"jiraBase\" is not defined"),
+			},
+			{
+				description: "reserved vars param",
+				config: config.Config{
+					Vars: map[string]string{"base": "https://example.com"},
+					Routes: []config.Route{buildTestRoute(func(route *config.Route) {
+						route.Params["vars"] = "override"
+					})},
+				},
+				expectedError: errors.New("failed to parse vars for route at index [0]: param \"vars\" is reserved for the configuration vars"),
+			},
+		}
+
+		for _, test := range templateTests {
+			_, err := route.NewRoutes(test.config, route.Environment{})
+			if err == nil || err.Error() != test.expectedError.Error() {
+				t.Fatalf("%s: expected error %q but got %v", test.description, test.expectedError, err)
+			}
+		}
+	})
+
+	t.Run("fails with invalid trusted proxies", func(t *testing.T) {
+		t.Parallel()
+		_, err := route.NewRoutes(config.Config{
+			Server: config.Server{
+				TrustedProxies: []string{"proxy"},
+			},
+		}, route.Environment{})
+		expectedError := errors.New("failed to parse server client address configuration: failed to parse trusted proxies: invalid address \"proxy\": ParseAddr(\"proxy\"): unable to parse IP")
+		if err == nil || err.Error() != expectedError.Error() {
+			t.Fatalf("expected error %q but got %v", expectedError, err)
+		}
+	})
+
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 			t.Parallel()
-			_, err := route.NewRoutes([]config.Route{test.route})
+			_, err := route.NewRoutes(config.Config{Routes: []config.Route{test.route}}, route.Environment{})
 			if err == nil {
 				t.Fatalf("expected create routes to fail but got nil")
 			}
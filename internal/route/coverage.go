@@ -0,0 +1,136 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// CoverageItem is a part of a route that tests may exercise.
+type CoverageItem struct {
+	// Name describes the item, e.g. `path "/example"` or `check [0] failed`.
+	Name string
+
+	// Covered reports whether at least one test exercised the item.
+	Covered bool
+}
+
+// RouteCoverage is the test coverage of a single route.
+type RouteCoverage struct {
+	RouteIndex int
+	Path       string
+	Title      string
+	Items      []CoverageItem
+}
+
+// Covered returns the number of items exercised by at least one test.
+func (s RouteCoverage) Covered() int {
+	covered := 0
+	for _, item := range s.Items {
+		if item.Covered {
+			covered++
+		}
+	}
+
+	return covered
+}
+
+// coverageRecorder records the items of routes exercised by route tests.
+type coverageRecorder struct {
+	mu   sync.Mutex
+	hits map[int]map[string]bool
+}
+
+type coverageContextKey struct{}
+
+func withCoverage(ctx context.Context, recorder *coverageRecorder) context.Context {
+	return context.WithValue(ctx, coverageContextKey{}, recorder)
+}
+
+// recordCoverage marks the item of the route as exercised if the request is sent by a route test.
+func recordCoverage(ctx context.Context, route Route, item string) {
+	recorder, ok := ctx.Value(coverageContextKey{}).(*coverageRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.hits[route.index] == nil {
+		recorder.hits[route.index] = map[string]bool{}
+	}
+	recorder.hits[route.index][item] = true
+}
+
+func (s *coverageRecorder) coverage(routes []Route) []RouteCoverage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]RouteCoverage, 0, len(routes))
+	for _, route := range routes {
+		coverage := RouteCoverage{
+			RouteIndex: route.index,
+			Path:       route.paths[0],
+			Title:      route.title,
+		}
+		for _, item := range route.coverageItems() {
+			coverage.Items = append(coverage.Items, CoverageItem{Name: item, Covered: s.hits[route.index][item]})
+		}
+		result = append(result, coverage)
+	}
+
+	return result
+}
+
+// coverageItems returns the items of the route tests may exercise in order:
+// the path and aliases, the params, both outcomes of each check and the redirect targets which may be chosen.
+func (s Route) coverageItems() []string {
+	items := []string{}
+	for _, path := range s.paths {
+		items = append(items, coveragePath(path))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(s.params)) {
+		items = append(items, coverageParam(key))
+	}
+
+	for idx := range s.checks {
+		items = append(items, coverageCheck(idx, true), coverageCheck(idx, false))
+	}
+
+	for _, target := range s.redirect.targets {
+		if target.weight > 0 {
+			items = append(items, coverageTarget(target))
+		}
+	}
+
+	return items
+}
+
+func coveragePath(path string) string {
+	return fmt.Sprintf("path %q", path)
+}
+
+// coverageParam is exercised if the param resolves to a non-empty value.
+func coverageParam(key string) string {
+	return fmt.Sprintf("param %q", key)
+}
+
+func coverageCheck(idx int, passed bool) string {
+	if passed {
+		return fmt.Sprintf("check [%d] passed", idx)
+	}
+
+	return fmt.Sprintf("check [%d] failed", idx)
+}
+
+func coverageTarget(target RouteRedirectTarget) string {
+	if target.name == "" {
+		return "redirect url"
+	}
+
+	return fmt.Sprintf("redirect target %q", target.name)
+}
@@ -0,0 +1,115 @@
+package route
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var interstitialTemplate = template.Must(template.ParseFS(templates, "templates/deprecated.html.tmpl"))
+
+type RouteDeprecation struct {
+	path         string
+	message      string
+	sunset       time.Time
+	interstitial bool
+	hits         *atomic.Int64
+}
+
+type interstitialInput struct {
+	Path    string
+	Message string
+	Sunset  string
+	URL     string
+}
+
+func parseRouteDeprecations(deprecations []config.RouteDeprecation, paths []string) (map[string]RouteDeprecation, error) {
+	known := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		known[path] = true
+	}
+
+	result := make(map[string]RouteDeprecation, len(deprecations))
+	for _, deprecation := range deprecations {
+		if !known[deprecation.Path] {
+			return nil, fmt.Errorf("deprecated path %q is not the path or an alias of the route", deprecation.Path)
+		}
+		if _, ok := result[deprecation.Path]; ok {
+			return nil, fmt.Errorf("deprecated path %q is defined more than once", deprecation.Path)
+		}
+
+		resultDeprecation := RouteDeprecation{
+			path:         deprecation.Path,
+			message:      deprecation.Message,
+			interstitial: deprecation.Interstitial,
+			hits:         &atomic.Int64{},
+		}
+
+		if deprecation.Sunset != "" {
+			sunset, err := time.Parse(time.RFC3339, deprecation.Sunset)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse sunset for deprecated path %q: %w", deprecation.Path, err)
+			}
+			resultDeprecation.sunset = sunset
+		}
+
+		result[deprecation.Path] = resultDeprecation
+	}
+
+	return result, nil
+}
+
+// sunsetPassed reports whether the deprecated path has passed its sunset date at the given time.
+func (s RouteDeprecation) sunsetPassed(now time.Time) bool {
+	return !s.sunset.IsZero() && !now.Before(s.sunset)
+}
+
+// writeHeaders records the deprecated hit and sets the deprecation response headers.
+func (s RouteDeprecation) writeHeaders(w http.ResponseWriter) {
+	hits := s.hits.Add(1)
+	slog.Warn("deprecated path requested", "path", s.path, "hits", hits)
+
+	w.Header().Set("Deprecation", "true")
+	if !s.sunset.IsZero() {
+		w.Header().Set("Sunset", s.sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (s RouteDeprecation) writeSunset(w http.ResponseWriter) {
+	message := fmt.Sprintf("%s has been removed", s.path)
+	if s.message != "" {
+		message += ": " + s.message
+	}
+
+	http.Error(w, message, http.StatusGone)
+}
+
+func (s RouteDeprecation) writeInterstitial(w http.ResponseWriter, url string) {
+	input := interstitialInput{
+		Path:    s.path,
+		Message: s.message,
+		URL:     url,
+	}
+	if !s.sunset.IsZero() {
+		input.Sunset = s.sunset.Format(time.RFC3339)
+	}
+
+	buffer, release := getBuffer()
+	defer release()
+	if err := interstitialTemplate.Execute(buffer, input); err != nil {
+		http.Error(w, fmt.Sprintf("failed to render deprecation page: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buffer.Bytes())
+}
@@ -0,0 +1,66 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+)
+
+// Destination is a redirect destination of a route known without serving a request.
+type Destination struct {
+	RouteIndex int
+	Path       string
+	Title      string
+
+	// Source describes where the destination is defined, e.g. `test [0]` or `redirect url`.
+	Source string
+
+	URL string
+}
+
+// Destinations returns the expected redirect urls of the tests of every route followed by its redirect urls which do not depend on the request.
+func Destinations(routes []Route) []Destination {
+	result := []Destination{}
+	for idx, route := range routes {
+		if !route.valid {
+			panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
+		}
+
+		destination := Destination{RouteIndex: idx, Path: route.paths[0], Title: route.title}
+		for _, test := range route.tests {
+			if test.response.url != "" {
+				destination.Source = test.name()
+				destination.URL = test.response.url
+				result = append(result, destination)
+			}
+		}
+
+		for _, target := range route.redirect.targets {
+			if url, ok := fixedTemplateText(target.url.Tree); ok && target.weight > 0 {
+				destination.Source = coverageTarget(target)
+				destination.URL = url
+				result = append(result, destination)
+			}
+		}
+	}
+
+	return result
+}
+
+// fixedTemplateText returns the output of the template if it only consists of literal text.
+func fixedTemplateText(tree *parse.Tree) (string, bool) {
+	if tree == nil || tree.Root == nil {
+		return "", false
+	}
+
+	builder := &strings.Builder{}
+	for _, node := range tree.Root.Nodes {
+		text, ok := node.(*parse.TextNode)
+		if !ok {
+			return "", false
+		}
+		builder.Write(text.Text)
+	}
+
+	return builder.String(), builder.Len() > 0
+}
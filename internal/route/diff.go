@@ -0,0 +1,178 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Diff describes how the behaviour of two sets of routes differs.
+type Diff struct {
+	// AddedRoutes and RemovedRoutes are the sorted paths of the routes only present in the new or old routes.
+	AddedRoutes   []string
+	RemovedRoutes []string
+
+	// Requests is the number of requests replayed through both sets of routes.
+	Requests int
+
+	// Changes are the replayed requests the routes responded to differently in replay order.
+	Changes []DiffChange
+}
+
+// DiffChange is a request the old and new routes responded to differently.
+type DiffChange struct {
+	Method string
+	URL    string
+	Old    DiffResponse
+	New    DiffResponse
+}
+
+// NewlyFailingCheck reports whether the request fails a check of the new routes but did not fail any check of the old routes.
+func (s DiffChange) NewlyFailingCheck() bool {
+	return s.New.FailedCheck != "" && s.Old.FailedCheck == ""
+}
+
+// DiffResponse is how a set of routes responded to a replayed request.
+type DiffResponse struct {
+	// Route is the path of the matched route. Empty if no route matched.
+	Route string
+
+	Status   int
+	Location string
+
+	// FailedCheck is the expression of the check the request failed, if any.
+	FailedCheck string
+}
+
+// DiffRoutes replays the test requests of both sets of routes followed by GET requests for the urls through the handlers of both and returns the differences.
+// Every request is replayed once. Requests are evaluated at the same time for both unless the test pins the time and
+// weighted redirect targets are chosen from the first bucket unless the test pins the bucket. Rate limits do not apply.
+func DiffRoutes(ctx context.Context, oldRoutes []Route, newRoutes []Route, urls []string) (Diff, error) {
+	requests := []RouteTestRequest{}
+	seen := map[string]bool{}
+	add := func(request RouteTestRequest) {
+		key := request.key()
+		if !seen[key] {
+			seen[key] = true
+			requests = append(requests, request)
+		}
+	}
+	for _, routes := range [][]Route{oldRoutes, newRoutes} {
+		for idx, route := range routes {
+			if !route.valid {
+				panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
+			}
+			for _, test := range route.tests {
+				add(test.request)
+			}
+		}
+	}
+	for _, url := range urls {
+		if !strings.HasPrefix(url, "/") {
+			return Diff{}, fmt.Errorf("url %q must be an absolute path (start with slash)", url)
+		}
+		if _, err := neturl.ParseRequestURI(url); err != nil {
+			return Diff{}, fmt.Errorf("url %q is not a valid request url: %w", url, err)
+		}
+		add(RouteTestRequest{method: http.MethodGet, url: url})
+	}
+
+	result := Diff{
+		AddedRoutes:   routePathsMissingFrom(newRoutes, oldRoutes),
+		RemovedRoutes: routePathsMissingFrom(oldRoutes, newRoutes),
+	}
+
+	now := time.Now()
+	oldMux := newTestMux(NewHandlers(oldRoutes))
+	newMux := newTestMux(NewHandlers(newRoutes))
+	for _, request := range requests {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		oldResponse := replay(ctx, oldMux, request, now)
+		newResponse := replay(ctx, newMux, request, now)
+		result.Requests++
+		if oldResponse != newResponse {
+			result.Changes = append(result.Changes, DiffChange{
+				Method: request.method,
+				URL:    request.url,
+				Old:    oldResponse,
+				New:    newResponse,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// replay sends the request through the mux and returns how it was responded to.
+func replay(ctx context.Context, mux *http.ServeMux, request RouteTestRequest, now time.Time) DiffResponse {
+	trace := &Trace{}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, newTestRequest(replayContext(ctx, request, trace, now), request))
+
+	result := DiffResponse{
+		Status:   w.Code,
+		Location: w.Header().Get("Location"),
+	}
+	if trace.Route != nil {
+		result.Route = trace.Route.Path
+	}
+	if len(trace.Checks) > 0 && !trace.Checks[len(trace.Checks)-1].Passed {
+		result.FailedCheck = trace.Checks[len(trace.Checks)-1].Expr
+	}
+
+	return result
+}
+
+// replayContext returns the context to replay the request with outside of route tests.
+// Rate limits do not apply and the time and weighted redirect bucket default to the given time and the first bucket unless the request pins them.
+func replayContext(ctx context.Context, request RouteTestRequest, trace *Trace, now time.Time) context.Context {
+	ctx = withEnvironmentOverrides(ctx, request.environment)
+	ctx = withRateLimitScope(ctx, false)
+	if !request.time.IsZero() {
+		now = request.time
+	}
+	ctx = withTime(ctx, now)
+	bucket := 0
+	if request.bucket != nil {
+		bucket = *request.bucket
+	}
+	ctx = withBucket(ctx, bucket)
+
+	return withTrace(ctx, trace)
+}
+
+// key identifies the request to replay each distinct request once.
+func (s RouteTestRequest) key() string {
+	bucket := "none"
+	if s.bucket != nil {
+		bucket = fmt.Sprint(*s.bucket)
+	}
+
+	return fmt.Sprintf("%s %s %v %v %v %s %s %s", s.method, s.url, s.headers, s.cookies, s.environment.values, s.time.Format(time.RFC3339), s.remoteAddr, bucket)
+}
+
+// routePathsMissingFrom returns the sorted paths of the routes not present in the other routes.
+func routePathsMissingFrom(routes []Route, other []Route) []string {
+	paths := map[string]bool{}
+	for _, route := range other {
+		paths[route.paths[0]] = true
+	}
+
+	result := []string{}
+	for _, route := range routes {
+		if !paths[route.paths[0]] {
+			result = append(result, route.paths[0])
+		}
+	}
+	slices.Sort(result)
+
+	return result
+}
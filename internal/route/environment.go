@@ -0,0 +1,66 @@
+package route
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// Environment is an immutable snapshot of the environment variables routes may resolve values from.
+// The zero value is an empty environment.
+type Environment struct {
+	values map[string]string
+}
+
+// NewEnvironment creates an environment snapshot from the given values.
+// The values are copied so later changes to the input map are not visible to the snapshot.
+func NewEnvironment(values map[string]string) Environment {
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		result[key] = value
+	}
+
+	return Environment{values: result}
+}
+
+// SnapshotEnvironment creates an environment snapshot from the current process environment.
+func SnapshotEnvironment() Environment {
+	environ := os.Environ()
+	values := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		values[key] = value
+	}
+
+	return Environment{values: values}
+}
+
+func (s Environment) lookup(key string) (string, bool) {
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// filter returns a new snapshot containing only the allowed keys.
+func (s Environment) filter(allowed map[string]bool) Environment {
+	result := make(map[string]string, len(allowed))
+	for key := range allowed {
+		if value, ok := s.values[key]; ok {
+			result[key] = value
+		}
+	}
+
+	return Environment{values: result}
+}
+
+type environmentContextKey struct{}
+
+// withEnvironmentOverrides attaches environment values to the request context which take precedence
+// over the route environment snapshot. Used by route tests to supply their own environment per test case.
+func withEnvironmentOverrides(ctx context.Context, env Environment) context.Context {
+	return context.WithValue(ctx, environmentContextKey{}, env)
+}
+
+func environmentOverridesFromContext(ctx context.Context) (Environment, bool) {
+	env, ok := ctx.Value(environmentContextKey{}).(Environment)
+	return env, ok
+}
@@ -0,0 +1,244 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+// FuzzFailure is a generated request a route handled incorrectly.
+type FuzzFailure struct {
+	RouteIndex int
+	Path       string
+	Title      string
+
+	// Message describes what went wrong, e.g. the redirect url containing CR or LF.
+	Message string
+
+	// Test is a route test sending the request which reproduces the failure.
+	// It expects the request to be rejected with FuzzExpectedBody as error message, so it fails until the route is fixed.
+	Test config.RouteTest
+}
+
+// FuzzExpectedBody is the error message the tests reproducing fuzz failures expect. No route responds with it, so the tests fail
+// against the route as is, including template execution failures already responded to with 400 Bad Request.
+// It is meant to be replaced with the message of the check rejecting the input or the expected response of the fixed route.
+const FuzzExpectedBody = "replace with the error message rejecting the input"
+
+// fuzzValues are the unexpected inputs path wildcards, query params and headers are set to.
+var fuzzValues = []string{
+	"",
+	" ",
+	"%",
+	"%25",
+	"%zz",
+	"%00",
+	"a b",
+	"../..",
+	"..%2f..",
+	"a/b",
+	"ü",
+	"日本語",
+	"😀",
+	"\u202e",
+	"?x=1",
+	"#fragment",
+	`\`,
+	`'"<>`,
+	"{{.}}",
+	"javascript:alert(1)",
+	"//evil.example",
+	"\r\nLocation: https://evil.example",
+	"\nX-Injected: 1",
+	"%0d%0aSet-Cookie: a=b",
+	strings.Repeat("a", 2048),
+}
+
+// fuzzHeaderValues are the fuzz values which may be sent as header values.
+var fuzzHeaderValues = slices.DeleteFunc(slices.Clone(fuzzValues), func(value string) bool {
+	return strings.ContainsAny(value, "\r\n\x00")
+})
+
+var pathWildcardPattern = regexp.MustCompile(`\{([^}]*)\}`)
+
+// Fuzz sends requests generated for every route through the handlers of the routes and returns the requests the routes handled incorrectly.
+// The requests of each route are generated from its path wildcards, the query params and headers its templates reference and its tests.
+// A request fails if executing a template fails, the redirect url is not a valid url or contains CR or LF or the handler panics.
+// Each kind of failure is reported once per route. The same seed generates the same requests.
+func Fuzz(ctx context.Context, routes []Route, requests int, seed uint64) []FuzzFailure {
+	mux := newTestMux(NewHandlers(routes))
+	now := time.Now()
+
+	result := []FuzzFailure{}
+	for idx, route := range routes {
+		rng := rand.New(rand.NewPCG(seed, uint64(idx)))
+		queries, headers := route.fuzzInputs()
+		reported := map[string]bool{}
+		for range requests {
+			if ctx.Err() != nil {
+				return result
+			}
+
+			request := route.fuzzRequest(rng, queries, headers)
+			trace := &Trace{}
+			message := sendFuzzRequest(replayContext(ctx, request, trace, now), mux, request, trace)
+			if message == "" || trace.Route == nil || trace.Route.Index != route.index || reported[message] {
+				continue
+			}
+			reported[message] = true
+
+			result = append(result, FuzzFailure{
+				RouteIndex: idx,
+				Path:       route.paths[0],
+				Title:      route.title,
+				Message:    message,
+				Test: config.RouteTest{
+					Request: request.config(),
+					Response: config.RouteTestResponse{
+						Status:     http.StatusBadRequest,
+						NoRedirect: true,
+						Body:       FuzzExpectedBody,
+					},
+				},
+			})
+		}
+	}
+
+	return result
+}
+
+// sendFuzzRequest sends the request and returns why it failed. Returns an empty string if the request did not fail.
+func sendFuzzRequest(ctx context.Context, mux *http.ServeMux, request RouteTestRequest, trace *Trace) (message string) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			message = fmt.Sprintf("handler panicked: %v", recovered)
+		}
+	}()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, newTestRequest(ctx, request))
+
+	if trace.TemplateError != "" {
+		return "template execution failed: " + trace.TemplateError
+	}
+
+	location := w.Header().Get("Location")
+	if strings.ContainsAny(location, "\r\n") {
+		return "redirect url contains CR or LF"
+	}
+	if location != "" {
+		if _, err := neturl.Parse(location); err != nil {
+			return "redirect url is not a valid url"
+		}
+	}
+
+	return ""
+}
+
+// fuzzInputs returns the sorted query param keys and header names the param templates of the route reference.
+func (s Route) fuzzInputs() ([]string, []string) {
+	queries := map[string]bool{}
+	headers := map[string]bool{}
+	for _, param := range s.params {
+		for _, key := range templateCallArgs(param, "GetQuery") {
+			queries[key] = true
+		}
+		for _, name := range templateCallArgs(param, "GetHeader") {
+			headers[name] = true
+		}
+	}
+
+	return slices.Sorted(maps.Keys(queries)), slices.Sorted(maps.Keys(headers))
+}
+
+// fuzzRequest generates a request for the route by filling the wildcards of one of its paths or altering one of its tests
+// and setting some of the query params and headers to unexpected values.
+func (s Route) fuzzRequest(rng *rand.Rand, queries []string, headers []string) RouteTestRequest {
+	request := RouteTestRequest{method: http.MethodGet}
+	if len(s.tests) > 0 && rng.IntN(2) == 0 {
+		request = s.tests[rng.IntN(len(s.tests))].request
+	} else {
+		request.url = pathWildcardPattern.ReplaceAllStringFunc(s.paths[rng.IntN(len(s.paths))], func(wildcard string) string {
+			if wildcard == "{$}" {
+				return ""
+			}
+			return neturl.PathEscape(fuzzValues[rng.IntN(len(fuzzValues))])
+		})
+	}
+
+	requestURL, err := neturl.Parse(request.url)
+	if err != nil {
+		return request
+	}
+	query := requestURL.Query()
+	for _, key := range queries {
+		if rng.IntN(2) == 0 {
+			query.Set(key, fuzzValues[rng.IntN(len(fuzzValues))])
+		}
+	}
+	requestURL.RawQuery = query.Encode()
+	request.url = requestURL.RequestURI()
+
+	request.headers = maps.Clone(request.headers)
+	for _, name := range headers {
+		if rng.IntN(2) == 0 {
+			if request.headers == nil {
+				request.headers = map[string]string{}
+			}
+			request.headers[name] = fuzzHeaderValues[rng.IntN(len(fuzzHeaderValues))]
+		}
+	}
+
+	return request
+}
+
+// config returns the configuration of a route test sending the request.
+func (s RouteTestRequest) config() config.RouteTestRequest {
+	result := config.RouteTestRequest{
+		URL:         s.url,
+		Headers:     s.headers,
+		Cookies:     s.cookies,
+		Environment: s.environment.values,
+		RemoteAddr:  s.remoteAddr,
+		Bucket:      s.bucket,
+	}
+	if s.method != http.MethodGet {
+		result.Method = s.method
+	}
+	if !s.time.IsZero() {
+		result.Time = s.time.Format(time.RFC3339)
+	}
+
+	return result
+}
+
+// templateCallArgs returns the string arguments the template and the named templates it references call the method with, e.g. the keys of {{.GetQuery "key"}}.
+func templateCallArgs(tmpl *template.Template, method string) []string {
+	result := []string{}
+	walkTemplate(tmpl, func(node parse.Node) {
+		cmd, ok := node.(*parse.CommandNode)
+		if !ok || len(cmd.Args) != 2 {
+			return
+		}
+
+		field, isField := cmd.Args[0].(*parse.FieldNode)
+		arg, isString := cmd.Args[1].(*parse.StringNode)
+		if isField && isString && field.Ident[len(field.Ident)-1] == method {
+			result = append(result, arg.Text)
+		}
+	})
+
+	return result
+}
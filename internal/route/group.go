@@ -0,0 +1,125 @@
+package route
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+// ExpandGroup returns the member routes of the group with the group configuration applied.
+// The paths, aliases, deprecations and test request urls of the member routes are prefixed with the group prefix,
+// the group environment allowlist and params are added, the group checks are evaluated before the route checks
+// and the group authentication is required. The group ip access is applied by NewRoutes.
+func ExpandGroup(group config.RouteGroup) ([]config.Route, error) {
+	if !strings.HasPrefix(group.Prefix, "/") {
+		return nil, fmt.Errorf("prefix %q must be an absolute path (start with slash)", group.Prefix)
+	}
+	prefix := strings.TrimSuffix(group.Prefix, "/")
+
+	result := make([]config.Route, 0, len(group.Routes))
+	for idx, route := range group.Routes {
+		for _, path := range append([]string{route.Path}, route.Aliases...) {
+			if !strings.HasPrefix(path, "/") {
+				return nil, fmt.Errorf("path or alias %q of route at index [%d] must be an absolute path (start with slash)", path, idx)
+			}
+		}
+		for key := range route.Params {
+			if _, ok := group.Params[key]; ok {
+				return nil, fmt.Errorf("param %q of route at index [%d] is already defined by the group", key, idx)
+			}
+		}
+		if group.Authentication != "" && route.Authentication != "" && route.Authentication != group.Authentication {
+			return nil, fmt.Errorf("authentication %q of route at index [%d] differs from the group authentication %q", route.Authentication, idx, group.Authentication)
+		}
+
+		route.Path = prefix + route.Path
+		route.Aliases = prefixPaths(prefix, route.Aliases)
+
+		deprecations := make([]config.RouteDeprecation, 0, len(route.Deprecations))
+		for _, deprecation := range route.Deprecations {
+			deprecation.Path = prefix + deprecation.Path
+			deprecations = append(deprecations, deprecation)
+		}
+		route.Deprecations = deprecations
+
+		tests := make([]config.RouteTest, 0, len(route.Tests))
+		for _, test := range route.Tests {
+			if strings.HasPrefix(test.Request.URL, "/") {
+				test.Request.URL = prefix + test.Request.URL
+			}
+			tests = append(tests, test)
+		}
+		route.Tests = tests
+
+		allowlist := slices.Clone(group.Environment.Allowlist)
+		for _, key := range route.Environment.Allowlist {
+			if !slices.Contains(allowlist, key) {
+				allowlist = append(allowlist, key)
+			}
+		}
+		route.Environment.Allowlist = allowlist
+
+		params := make(map[string]string, len(group.Params)+len(route.Params))
+		for key, value := range group.Params {
+			params[key] = value
+		}
+		for key, value := range route.Params {
+			params[key] = value
+		}
+		route.Params = params
+
+		route.Checks = append(slices.Clone(group.Checks), route.Checks...)
+
+		if group.Authentication != "" {
+			route.Authentication = group.Authentication
+		}
+
+		result = append(result, route)
+	}
+
+	return result, nil
+}
+
+// expandRoutes returns the routes followed by the expanded member routes of each group.
+func expandRoutes(conf config.Config) ([]config.Route, error) {
+	result := slices.Clone(conf.Routes)
+	for idx, group := range conf.Groups {
+		routes, err := ExpandGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand group at index [%d]: %w", idx, err)
+		}
+		result = append(result, routes...)
+	}
+
+	return result, nil
+}
+
+// routeGroups returns the index of the group of every route returned by expandRoutes. Ungrouped routes have the index -1.
+func routeGroups(conf config.Config) []int {
+	result := make([]int, 0, len(conf.Routes))
+	for range conf.Routes {
+		result = append(result, -1)
+	}
+	for idx, group := range conf.Groups {
+		for range group.Routes {
+			result = append(result, idx)
+		}
+	}
+
+	return result
+}
+
+func prefixPaths(prefix string, paths []string) []string {
+	if paths == nil {
+		return nil
+	}
+
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		result = append(result, prefix+path)
+	}
+
+	return result
+}
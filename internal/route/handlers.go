@@ -3,13 +3,20 @@ package route
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/cel-go/common/types"
+	"github.com/slightly-inconvenient/murl/internal/auth"
 )
 
 var bufferPool = sync.Pool{
@@ -19,28 +26,72 @@ var bufferPool = sync.Pool{
 }
 
 type paramsInput struct {
-	getPath   func(key string) string
-	getQuery  func(key string) string
-	getHeader func(key string) string
-	getEnv    func(key string) string
+	getPath     func(key string) string
+	getQuery    func(key string) string
+	getHeader   func(key string) string
+	getEnv      func(key string) string
+	getLanguage func() (string, error)
+	vars        map[string]string
+	identity    auth.Identity
+	clientCert  clientCertificate
+
+	// traced enables collecting the inputs read by the template being executed in reads.
+	traced bool
+	reads  []string
 }
 
 func (s *paramsInput) GetPath(key string) string {
+	s.read(fmt.Sprintf("path %q", key))
 	return s.getPath(key)
 }
 
 func (s *paramsInput) GetQuery(key string) string {
+	s.read(fmt.Sprintf("query %q", key))
 	return s.getQuery(key)
 }
 
 func (s *paramsInput) GetHeader(key string) string {
+	s.read(fmt.Sprintf("header %q", key))
 	return s.getHeader(key)
 }
 
 func (s *paramsInput) GetEnv(key string) string {
+	s.read(fmt.Sprintf("env %q", key))
 	return s.getEnv(key)
 }
 
+func (s *paramsInput) GetLanguage() (string, error) {
+	s.read(`header "Accept-Language"`)
+	return s.getLanguage()
+}
+
+func (s *paramsInput) GetVar(key string) string {
+	s.read(fmt.Sprintf("var %q", key))
+	return s.vars[key]
+}
+
+func (s *paramsInput) GetUser() string {
+	s.read("user")
+	return s.identity.User
+}
+
+func (s *paramsInput) InGroup(group string) bool {
+	s.read(fmt.Sprintf("group %q", group))
+	return s.identity.InGroup(group)
+}
+
+func (s *paramsInput) GetClientCert() clientCertificate {
+	s.read("client certificate")
+	return s.clientCert
+}
+
+// read records the input read by the template being executed if the request is traced.
+func (s *paramsInput) read(source string) {
+	if s.traced && !slices.Contains(s.reads, source) {
+		s.reads = append(s.reads, source)
+	}
+}
+
 type Handler struct {
 	path    string
 	handler http.HandlerFunc
@@ -60,37 +111,82 @@ func NewHandlers(routes []Route) []Handler {
 		if !route.valid {
 			panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
 		}
-		handler := createRouteHandler(route)
 		for _, path := range route.paths {
-			handlers = append(handlers, Handler{path: path, handler: handler})
+			var deprecation *RouteDeprecation
+			if value, ok := route.deprecations[path]; ok {
+				deprecation = &value
+			}
+			handlers = append(handlers, Handler{path: path, handler: createRouteHandler(route, path, deprecation)})
 		}
 	}
 	return handlers
 }
 
+// TestHandlers runs the tests of all routes against the handlers.
+// Returns the failures of all failed tests joined if any test failed. Use RunTests for the result of every test.
 func TestHandlers(ctx context.Context, routes []Route, handlers []Handler) error {
-	mux := http.NewServeMux()
-	for _, handler := range handlers {
-		mux.HandleFunc(handler.Route(), handler.Handler())
+	errs := []error{}
+	for _, result := range RunTests(ctx, routes, handlers) {
+		if result.Failure != nil {
+			errs = append(errs, fmt.Errorf("%s failed: %w", result.Name(), result.Failure))
+		}
 	}
 
-	for idx, route := range routes {
-		if !route.valid {
-			panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
+	return errors.Join(errs...)
+}
+
+func createRouteHandler(route Route, path string, deprecation *RouteDeprecation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordCoverage(r.Context(), route, coveragePath(path))
+		trace := traceFromContext(r.Context())
+		trace.match(route, path)
+
+		clientAddr := route.clientIP.Resolve(r)
+		for _, access := range route.ipAccess {
+			if !access.allowed(clientAddr) {
+				access.writeDenied(w, r)
+				return
+			}
 		}
 
-		for _, test := range route.tests {
-			if err := testRoute(ctx, mux, test); err != nil {
-				return err
+		now := requestTime(r)
+
+		// Limits not keyed by a param are applied before authentication so rejected credentials also consume the limits
+		for _, limit := range route.rateLimits {
+			if limit.param != "" {
+				continue
+			}
+			if ok, wait := limit.allow(r, clientAddr, nil, now); !ok {
+				writeRateLimited(w, wait)
+				return
 			}
 		}
-	}
 
-	return nil
-}
+		identity := auth.Identity{}
+		if route.authenticator != nil {
+			authenticated, ok := route.authenticator.Authenticate(r)
+			if !ok {
+				route.authenticator.Challenge(w)
+				return
+			}
+			identity = authenticated
+			logAttrs(r.Context(), slog.String("user", identity.User))
+		}
+
+		if !route.window.active(now) {
+			route.window.writeInactive(w, r, now)
+			return
+		}
+
+		if deprecation != nil {
+			if deprecation.sunsetPassed(now) {
+				deprecation.writeSunset(w)
+				return
+			}
+
+			deprecation.writeHeaders(w)
+		}
 
-func createRouteHandler(route Route) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
 		input := &paramsInput{
 			getPath: func(key string) string {
 				return r.PathValue(key)
@@ -102,38 +198,84 @@ func createRouteHandler(route Route) http.HandlerFunc {
 				return r.Header.Get(key)
 			},
 			getEnv: func(key string) string {
-				if route.environment.allowedEnvVariables[key] {
-					return os.Getenv(key)
+				if !route.environment.allowedEnvVariables[key] {
+					return ""
+				}
+
+				if overrides, ok := environmentOverridesFromContext(r.Context()); ok {
+					if value, ok := overrides.lookup(key); ok {
+						return value
+					}
 				}
 
-				return ""
+				value, _ := route.environment.snapshot.lookup(key)
+				return value
 			},
+			getLanguage: func() (string, error) {
+				return route.languages.negotiate(r.Header.Get("Accept-Language"))
+			},
+			vars:       route.vars,
+			identity:   identity,
+			clientCert: requestClientCertificate(r),
+			traced:     trace != nil,
 		}
 
 		params := map[string]any{}
+		redactedKeys := []string{}
 		for key, expr := range route.params {
 			buffer, release := getBuffer()
 			defer release()
 
+			input.reads = nil
 			err := expr.Execute(buffer, input)
 			if err != nil {
+				trace.templateError(err)
 				http.Error(w, fmt.Sprintf("failed to parse param for key %q: %s", key, err), http.StatusBadRequest)
 				return
 			}
 			params[key] = buffer.String()
+			trace.param(key, expr, buffer.String(), input.reads)
+			if trace.readsRedacted(input.reads) {
+				redactedKeys = append(redactedKeys, key)
+			}
+			if buffer.Len() > 0 {
+				recordCoverage(r.Context(), route, coverageParam(key))
+			}
+		}
+		if route.authenticator != nil {
+			params[authParam] = authParamValue(identity)
+		}
+		if route.clientCert {
+			params[clientCertParam] = clientCertParamValue(input.clientCert)
+		}
+		if route.vars != nil {
+			params[varsParam] = route.vars
+		}
+
+		for _, limit := range route.rateLimits {
+			if limit.param == "" {
+				continue
+			}
+			if ok, wait := limit.allow(r, clientAddr, params, now); !ok {
+				writeRateLimited(w, wait)
+				return
+			}
 		}
 
-		for _, check := range route.checks {
+		for idx, check := range route.checks {
 			out, _, err := check.expr.Eval(params)
+			trace.check(idx, check, err == nil && out == types.True)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("failed to evaluate check expression: %s", err), http.StatusBadRequest)
 				return
 			}
 
+			recordCoverage(r.Context(), route, coverageCheck(idx, out == types.True))
 			if out != types.True {
 				buffer, release := getBuffer()
 				defer release()
 				if err := check.error.Execute(buffer, params); err != nil {
+					trace.templateError(err)
 					http.Error(w, fmt.Sprintf("failed to render check error: %s", err), http.StatusBadRequest)
 					return
 				}
@@ -143,14 +285,40 @@ func createRouteHandler(route Route) http.HandlerFunc {
 			}
 		}
 
+		target := route.redirect.choose(w, r, params)
+		recordCoverage(r.Context(), route, coverageTarget(target))
+		trace.target(target)
+		if target.name != "" {
+			logAttrs(r.Context(), slog.String("variant", target.name))
+		}
+
 		buffer, release := getBuffer()
 		defer release()
-		if err := route.redirect.url.Execute(buffer, params); err != nil {
+		if err := target.url.Execute(buffer, params); err != nil {
+			trace.templateError(err)
 			http.Error(w, fmt.Sprintf("failed to create redirect url: %s", err), http.StatusBadRequest)
 			return
 		}
 
 		redirect := buffer.String()
+		if len(redactedKeys) > 0 {
+			redactedParams := maps.Clone(params)
+			for _, key := range redactedKeys {
+				redactedParams[key] = RedactedValue
+			}
+			redactedBuffer, release := getBuffer()
+			defer release()
+			if err := target.url.Execute(redactedBuffer, redactedParams); err != nil {
+				redactedBuffer.Reset()
+			}
+			trace.redactedRedirect(redactedBuffer.String())
+		}
+
+		if deprecation != nil && deprecation.interstitial {
+			deprecation.writeInterstitial(w, redirect)
+			return
+		}
+
 		http.Redirect(w, r, redirect, http.StatusTemporaryRedirect)
 	}
 }
@@ -164,60 +332,111 @@ func getBuffer() (*bytes.Buffer, func()) {
 	}
 }
 
-func testRoute(ctx context.Context, mux *http.ServeMux, test RouteTest) error {
-	req := httptest.NewRequestWithContext(ctx, http.MethodGet, test.request.url, nil)
-	for k, v := range test.request.headers {
-		req.Header.Add(k, v)
+func testRoute(ctx context.Context, mux *http.ServeMux, test RouteTest) *TestFailure {
+	ctx = withEnvironmentOverrides(ctx, test.request.environment)
+	ctx = withRateLimitScope(ctx, test.response.rateLimitedAfter > 0)
+	if !test.request.time.IsZero() {
+		ctx = withTime(ctx, test.request.time)
+	} else if test.response.rateLimitedAfter > 0 {
+		// Pin the time so rate limits do not refill between the repeated requests
+		ctx = withTime(ctx, time.Now())
+	}
+	if test.request.bucket != nil {
+		ctx = withBucket(ctx, *test.request.bucket)
 	}
 
-	clear := setCheckEnvironment(test.request.environment)
-	defer clear()
-
-	w := httptest.NewRecorder()
-	mux.ServeHTTP(w, req)
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, newTestRequest(ctx, test.request))
+		return w
+	}
 
-	if w.Code != test.response.status {
-		return fmt.Errorf("expected status %d but got %d", test.response.status, w.Code)
+	for idx := 0; idx < max(test.response.rateLimitedAfter, 1); idx++ {
+		w := serve()
+		if failure := checkTestResponse(w.Code, w.Header(), w.Body.String(), test.response); failure != nil {
+			return failure
+		}
 	}
 
-	if w.Header().Get("Location") != test.response.url {
-		return fmt.Errorf("expected redirect to %q but got %q", test.response.url, w.Header().Get("Location"))
+	if test.response.rateLimitedAfter > 0 {
+		if w := serve(); w.Code != http.StatusTooManyRequests {
+			return &TestFailure{
+				Message:  fmt.Sprintf("expected status %d after %d requests but got %d", http.StatusTooManyRequests, test.response.rateLimitedAfter, w.Code),
+				Expected: strconv.Itoa(http.StatusTooManyRequests),
+				Actual:   strconv.Itoa(w.Code),
+			}
+		}
 	}
 
 	return nil
 }
 
-func setCheckEnvironment(env map[string]string) func() {
-	setEnv := func(key string, value string) {
-		if err := os.Setenv(key, value); err != nil {
-			panic(fmt.Errorf("failed to set env %w", err))
-		}
+// newTestRequest creates the request sent for the test request.
+func newTestRequest(ctx context.Context, request RouteTestRequest) *http.Request {
+	req := httptest.NewRequestWithContext(ctx, request.method, request.url, nil)
+	if request.remoteAddr != "" {
+		req.RemoteAddr = request.remoteAddr
+	}
+	for k, v := range request.headers {
+		req.Header.Add(k, v)
 	}
-	unsetEnv := func(key string) {
-		if err := os.Unsetenv(key); err != nil {
-			panic(fmt.Errorf("failed to unset env %w", err))
+	for name, value := range request.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	return req
+}
+
+// checkTestResponse compares the status, headers and body of a response to the expected response of a test.
+func checkTestResponse(status int, header http.Header, body string, expected RouteTestResponse) *TestFailure {
+	if expected.status != 0 && status != expected.status {
+		return &TestFailure{
+			Message:  fmt.Sprintf("expected status %d but got %d", expected.status, status),
+			Expected: strconv.Itoa(expected.status),
+			Actual:   strconv.Itoa(status),
 		}
 	}
 
-	originalEnv := map[string]*string{}
-	for key, newValue := range env {
-		existing, existed := os.LookupEnv(key)
-		if existed {
-			originalEnv[key] = &existing
-		} else {
-			originalEnv[key] = nil
+	location := header.Get("Location")
+	if expected.noRedirect && (location != "" || isRedirectStatus(status)) {
+		return &TestFailure{
+			Message: fmt.Sprintf("expected no redirect but got status %d redirecting to %q", status, location),
+			Actual:  location,
 		}
+	}
+	if location != expected.url {
+		return &TestFailure{
+			Message:  fmt.Sprintf("expected redirect to %q but got %q", expected.url, location),
+			Expected: expected.url,
+			Actual:   location,
+		}
+	}
 
-		setEnv(key, newValue)
+	if expected.body != "" && !strings.Contains(body, expected.body) {
+		return &TestFailure{
+			Message:  fmt.Sprintf("expected body to contain %q but got %q", expected.body, body),
+			Expected: expected.body,
+			Actual:   body,
+		}
+	}
+	if expected.bodyRegex != nil && !expected.bodyRegex.MatchString(body) {
+		return &TestFailure{
+			Message:  fmt.Sprintf("expected body to match %q but got %q", expected.bodyRegex, body),
+			Expected: expected.bodyRegex.String(),
+			Actual:   body,
+		}
 	}
 
-	return func() {
-		for k, v := range originalEnv {
-			if v == nil {
-				unsetEnv(k)
-			} else {
-				setEnv(k, *v)
+	names := slices.Sorted(maps.Keys(expected.headers))
+	for _, name := range names {
+		if value := header.Get(name); value != expected.headers[name] {
+			return &TestFailure{
+				Message:  fmt.Sprintf("expected header %q to be %q but got %q", name, expected.headers[name], value),
+				Expected: expected.headers[name],
+				Actual:   value,
 			}
 		}
 	}
+
+	return nil
 }
@@ -2,10 +2,18 @@ package route_test
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -63,6 +71,7 @@ func TestHandler(t *testing.T) {
 	tests := []struct {
 		description   string
 		routes        []config.Route
+		environment   route.Environment
 		req           *http.Request
 		checkResponse func(*httptest.ResponseRecorder) error
 	}{
@@ -109,8 +118,11 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
+			environment: route.NewEnvironment(map[string]string{
+				"TEST_KEY_HOST":         "example.com",
+				"TEST_KEY_HOST_BLOCKED": "blocked.example.com",
+			}),
 			req: func() *http.Request {
-				os.Setenv("TEST_KEY_HOST", "example.com")
 				req := httptest.NewRequest("GET", "/example/wasd?q=xyz", nil)
 				req.Header.Set("x-test-header", "abc")
 				return req
@@ -153,13 +165,273 @@ func TestHandler(t *testing.T) {
 			req:           httptest.NewRequest("GET", "/example-alias", nil),
 			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://example.com"),
 		},
+		{
+			description: "route with negotiated language",
+			routes: []config.Route{
+				{
+					Path:      "/example",
+					Languages: []string{"en", "de", "fi"},
+					Params: map[string]string{
+						"lang":   `{{.GetLanguage}}`,
+						"accept": `{{.GetHeader "Accept-Language"}}`,
+					},
+					Checks: []config.RouteCheck{
+						{
+							Expr:  `language(accept) != "fi"`,
+							Error: "finnish is not available yet",
+						},
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com/{{.lang}}/policy",
+					},
+				},
+			},
+			req: func() *http.Request {
+				req := httptest.NewRequest("GET", "/example", nil)
+				req.Header.Set("Accept-Language", "fr;q=1.0, de-CH;q=0.9, en;q=0.5")
+				return req
+			}(),
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://example.com/de/policy"),
+		},
+		{
+			description: "route with negotiated language check",
+			routes: []config.Route{
+				{
+					Path:      "/example",
+					Languages: []string{"en", "fi"},
+					Params: map[string]string{
+						"accept": `{{.GetHeader "Accept-Language"}}`,
+					},
+					Checks: []config.RouteCheck{
+						{
+							Expr:  `language(accept) != "fi"`,
+							Error: "finnish is not available yet",
+						},
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+				},
+			},
+			req: func() *http.Request {
+				req := httptest.NewRequest("GET", "/example", nil)
+				req.Header.Set("Accept-Language", "fi-FI")
+				return req
+			}(),
+			checkResponse: createResponseChecker(http.StatusBadRequest, "finnish is not available yet"),
+		},
+		{
+			description: "route with language accessor without supported languages",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Params: map[string]string{
+						"lang": `{{.GetLanguage}}`,
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com/{{.lang}}",
+					},
+				},
+			},
+			req:           httptest.NewRequest("GET", "/example", nil),
+			checkResponse: createResponseChecker(http.StatusBadRequest, "failed to parse param for key \"lang\": template: :1:2: executing \"\" at <.GetLanguage>: error calling GetLanguage: route has no supported languages configured"),
+		},
+		{
+			description: "route with weighted targets",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Redirect: config.RouteRedirect{
+						Targets: []config.RouteRedirectTarget{
+							{Name: "old", URL: "https://old.example.com", Weight: 0},
+							{Name: "new", URL: "https://new.example.com", Weight: 1},
+						},
+					},
+				},
+			},
+			req:           httptest.NewRequest("GET", "/example", nil),
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://new.example.com"),
+		},
+		{
+			description: "route with sticky cookie target",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Redirect: config.RouteRedirect{
+						Targets: []config.RouteRedirectTarget{
+							{Name: "old", URL: "https://old.example.com", Weight: 1},
+							{Name: "new", URL: "https://new.example.com", Weight: 1},
+						},
+						Sticky: config.RouteRedirectSticky{Cookie: "variant"},
+					},
+				},
+			},
+			req: func() *http.Request {
+				req := httptest.NewRequest("GET", "/example", nil)
+				req.AddCookie(&http.Cookie{Name: "variant", Value: "old"})
+				return req
+			}(),
+			checkResponse: func(rec *httptest.ResponseRecorder) error {
+				if err := createResponseChecker(http.StatusTemporaryRedirect, "https://old.example.com")(rec); err != nil {
+					return err
+				}
+				if rec.Header().Get("Set-Cookie") != "" {
+					return fmt.Errorf("expected no new sticky cookie but got %q", rec.Header().Get("Set-Cookie"))
+				}
+				return nil
+			},
+		},
+		{
+			description: "route with sticky cookie assignment",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Redirect: config.RouteRedirect{
+						Targets: []config.RouteRedirectTarget{
+							{Name: "old", URL: "https://old.example.com", Weight: 0},
+							{Name: "new", URL: "https://new.example.com", Weight: 1},
+						},
+						Sticky: config.RouteRedirectSticky{Cookie: "variant"},
+					},
+				},
+			},
+			req: httptest.NewRequest("GET", "/example", nil),
+			checkResponse: func(rec *httptest.ResponseRecorder) error {
+				if err := createResponseChecker(http.StatusTemporaryRedirect, "https://new.example.com")(rec); err != nil {
+					return err
+				}
+				if !strings.HasPrefix(rec.Header().Get("Set-Cookie"), "variant=new;") {
+					return fmt.Errorf("expected sticky cookie but got %q", rec.Header().Get("Set-Cookie"))
+				}
+				return nil
+			},
+		},
+		{
+			description: "route before active window",
+			routes: []config.Route{
+				{
+					Path:       "/example",
+					ActiveFrom: "2999-01-01T00:00:00+02:00",
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+				},
+			},
+			req:           httptest.NewRequest("GET", "/example", nil),
+			checkResponse: createResponseChecker(http.StatusNotFound, "route is not active yet"),
+		},
+		{
+			description: "route after active window",
+			routes: []config.Route{
+				{
+					Path:        "/example",
+					ActiveUntil: "2000-01-01T00:00:00+02:00",
+					Inactive: config.RouteInactive{
+						Status: http.StatusGone,
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+				},
+			},
+			req:           httptest.NewRequest("GET", "/example", nil),
+			checkResponse: createResponseChecker(http.StatusGone, "route is no longer active"),
+		},
+		{
+			description: "route after active window with fallback",
+			routes: []config.Route{
+				{
+					Path:        "/example",
+					ActiveUntil: "2000-01-01T00:00:00+02:00",
+					Inactive: config.RouteInactive{
+						URL: "https://example.com/ended",
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+				},
+			},
+			req:           httptest.NewRequest("GET", "/example", nil),
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://example.com/ended"),
+		},
+		{
+			description: "route with deprecated alias",
+			routes: []config.Route{
+				{
+					Path:    "/example",
+					Aliases: []string{"/example-alias"},
+					Deprecations: []config.RouteDeprecation{
+						{Path: "/example-alias", Sunset: "2999-01-01T00:00:00Z"},
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+				},
+			},
+			req: httptest.NewRequest("GET", "/example-alias", nil),
+			checkResponse: func(rec *httptest.ResponseRecorder) error {
+				if err := createResponseChecker(http.StatusTemporaryRedirect, "https://example.com")(rec); err != nil {
+					return err
+				}
+				if rec.Header().Get("Deprecation") != "true" {
+					return fmt.Errorf("expected deprecation header but got %q", rec.Header().Get("Deprecation"))
+				}
+				if rec.Header().Get("Sunset") != "Tue, 01 Jan 2999 00:00:00 GMT" {
+					return fmt.Errorf("expected sunset header but got %q", rec.Header().Get("Sunset"))
+				}
+				return nil
+			},
+		},
+		{
+			description: "route with deprecated alias past sunset",
+			routes: []config.Route{
+				{
+					Path:    "/example",
+					Aliases: []string{"/example-alias"},
+					Deprecations: []config.RouteDeprecation{
+						{Path: "/example-alias", Message: "use /example instead", Sunset: "2000-01-01T00:00:00Z"},
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+				},
+			},
+			req:           httptest.NewRequest("GET", "/example-alias", nil),
+			checkResponse: createResponseChecker(http.StatusGone, "/example-alias has been removed: use /example instead"),
+		},
+		{
+			description: "route with deprecated path interstitial",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Deprecations: []config.RouteDeprecation{
+						{Path: "/example", Message: "use /other instead", Interstitial: true},
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+				},
+			},
+			req: httptest.NewRequest("GET", "/example", nil),
+			checkResponse: func(rec *httptest.ResponseRecorder) error {
+				if rec.Code != http.StatusOK {
+					return fmt.Errorf("expected status code %d but got %d", http.StatusOK, rec.Code)
+				}
+				for _, expected := range []string{"use /other instead", `<a href="https://example.com">`} {
+					if !strings.Contains(rec.Body.String(), expected) {
+						return fmt.Errorf("expected body to contain %q but got %q", expected, rec.Body.String())
+					}
+				}
+				return nil
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 			t.Parallel()
 
-			routes, err := route.NewRoutes(test.routes)
+			routes, err := route.NewRoutes(config.Config{Routes: test.routes}, test.environment)
 			if err != nil {
 				t.Fatalf("failed to create test routes: %v", err)
 			}
@@ -180,88 +452,2045 @@ func TestHandler(t *testing.T) {
 	}
 }
 
-func Test_TestHandlers(t *testing.T) {
+func TestHandler_RequestLog(t *testing.T) {
 	t.Parallel()
 
-	tests := []struct {
-		description string
-		routes      []config.Route
-		tests       []config.RouteTest
-	}{
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
 		{
-			description: "simple route with no params",
-			routes: []config.Route{
-				{
-					Path: "/example",
-					Redirect: config.RouteRedirect{
-						URL: "https://example.com",
-					},
-					Tests: []config.RouteTest{
-						{
-							Request: config.RouteTestRequest{
-								URL: "/example",
-							},
-							Response: config.RouteTestResponse{
-								URL: "https://example.com",
-							},
-						},
-					},
+			Path: "/example",
+			Redirect: config.RouteRedirect{
+				Targets: []config.RouteRedirectTarget{
+					{Name: "new", URL: "https://new.example.com", Weight: 1},
 				},
 			},
 		},
-		{
-			description: "complex params route",
-			routes: []config.Route{
-				{
-					Path: "/example/{id}",
-					Redirect: config.RouteRedirect{
-						URL: "https://{{.host}}/id/{{.id}}?a={{.query}}&b={{.header}}",
-					},
-					Environment: config.RouteEnvironment{
-						Allowlist: []string{"TEST_ENV_VAR"},
-					},
-					Params: map[string]string{
-						"id":     `{{.GetPath "id"}}`,
-						"query":  `{{.GetQuery "q"}}`,
-						"host":   `{{.GetEnv "TEST_ENV_VAR"}}`,
-						"header": `{{.GetHeader "x-test-header"}}`,
-					},
-					Tests: []config.RouteTest{
-						{
-							Request: config.RouteTestRequest{
-								URL: "/example/wasd?q=xyz",
-								Headers: map[string]string{
-									"x-test-header": "abc",
-								},
-								Environment: map[string]string{
-									"TEST_ENV_VAR": "test.local",
-								},
-							},
-							Response: config.RouteTestResponse{
-								URL: "https://test.local/id/wasd?a=xyz&b=abc",
-							},
-						},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	ctx, requestLog := route.WithRequestLog(context.Background())
+	rec := httptest.NewRecorder()
+	route.NewHandlers(routes)[0].Handler()(rec, httptest.NewRequestWithContext(ctx, "GET", "/example", nil))
+
+	attrs := requestLog.Attrs()
+	if len(attrs) != 1 || attrs[0].Key != "variant" || attrs[0].Value.String() != "new" {
+		t.Fatalf("expected variant to be recorded in request log but got %v", attrs)
+	}
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{
+		Server: config.Server{
+			TrustedProxies: []string{"10.0.0.0/8"},
+			RateLimit:      config.RateLimit{Requests: 3, Period: "1h"},
+		},
+		Routes: []config.Route{
+			{
+				Path:      "/example",
+				RateLimit: config.RateLimit{Requests: 1, Period: "1h", Key: config.RateLimitKey{Header: "x-user"}},
+				Redirect:  config.RouteRedirect{URL: "https://example.com"},
+			},
+			{
+				Path:     "/other",
+				Redirect: config.RouteRedirect{URL: "https://example.com"},
+			},
+		},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for _, handler := range route.NewHandlers(routes) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	serve := func(path string, user string, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("x-user", user)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	steps := []struct {
+		path               string
+		user               string
+		forwardedFor       string
+		expectedStatus     int
+		expectedRetryAfter string
+	}{
+		{path: "/example", user: "alice", forwardedFor: "203.0.113.1", expectedStatus: http.StatusTemporaryRedirect},
+		{path: "/example", user: "alice", forwardedFor: "203.0.113.1", expectedStatus: http.StatusTooManyRequests, expectedRetryAfter: "3600"},
+		{path: "/example", user: "bob", forwardedFor: "203.0.113.1", expectedStatus: http.StatusTemporaryRedirect},
+		{path: "/other", user: "alice", forwardedFor: "203.0.113.1", expectedStatus: http.StatusTooManyRequests, expectedRetryAfter: "1200"},
+		{path: "/other", user: "alice", forwardedFor: "203.0.113.2", expectedStatus: http.StatusTemporaryRedirect},
+	}
+
+	for idx, step := range steps {
+		rec := serve(step.path, step.user, step.forwardedFor)
+		if rec.Code != step.expectedStatus {
+			t.Fatalf("expected status %d for request [%d] but got %d", step.expectedStatus, idx, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != step.expectedRetryAfter {
+			t.Fatalf("expected retry after %q for request [%d] but got %q", step.expectedRetryAfter, idx, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestHandler_Authentication(t *testing.T) {
+	t.Parallel()
+
+	tokensFile := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokensFile, []byte("admin-token alice\nuser-token bob\n"), 0o600); err != nil {
+		t.Fatalf("failed to write tokens file: %v", err)
+	}
+
+	routes, err := route.NewRoutes(config.Config{
+		Authentication: map[string]config.AuthProvider{
+			"tokens": {
+				Bearer: config.AuthBearer{TokensFile: tokensFile},
+				Groups: map[string][]string{"admins": {"alice"}},
+			},
+		},
+		Routes: []config.Route{
+			{
+				Path:           "/admin",
+				Authentication: "tokens",
+				Params: map[string]string{
+					"user": `{{.GetUser}}{{if .InGroup "admins"}} (admin){{end}}`,
+				},
+				Checks: []config.RouteCheck{
+					{
+						Expr:  `"admins" in auth.groups`,
+						Error: "{{.auth.user}} is not an admin",
 					},
 				},
+				Redirect: config.RouteRedirect{URL: "https://admin.example.com/?user={{.user}}"},
 			},
 		},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for _, handler := range route.NewHandlers(routes) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	tests := []struct {
+		description   string
+		token         string
+		checkResponse func(*httptest.ResponseRecorder) error
+	}{
+		{
+			description: "rejects unauthenticated requests",
+			checkResponse: func(rec *httptest.ResponseRecorder) error {
+				if err := createResponseChecker(http.StatusUnauthorized, "authentication required")(rec); err != nil {
+					return err
+				}
+				if rec.Header().Get("WWW-Authenticate") != "Bearer" {
+					return fmt.Errorf("expected bearer challenge but got %q", rec.Header().Get("WWW-Authenticate"))
+				}
+				return nil
+			},
+		},
+		{
+			description:   "exposes identity to checks",
+			token:         "user-token",
+			checkResponse: createResponseChecker(http.StatusBadRequest, "bob is not an admin"),
+		},
+		{
+			description:   "exposes identity to params",
+			token:         "admin-token",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://admin.example.com/?user=alice (admin)"),
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 			t.Parallel()
-			ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second)
+
+			req := httptest.NewRequest("GET", "/admin", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if err := test.checkResponse(rec); err != nil {
+				t.Fatalf("unexpected response: %s", err)
+			}
+		})
+	}
+}
+
+func TestHandler_RateLimitAuthentication(t *testing.T) {
+	t.Parallel()
+
+	tokensFile := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokensFile, []byte("user-token bob\n"), 0o600); err != nil {
+		t.Fatalf("failed to write tokens file: %v", err)
+	}
+
+	routes, err := route.NewRoutes(config.Config{
+		Server: config.Server{
+			RateLimit: config.RateLimit{Requests: 3, Period: "1h"},
+		},
+		Authentication: map[string]config.AuthProvider{
+			"tokens": {Bearer: config.AuthBearer{TokensFile: tokensFile}},
+		},
+		Routes: []config.Route{
+			{
+				Path:           "/admin",
+				Authentication: "tokens",
+				Redirect:       config.RouteRedirect{URL: "https://admin.example.com"},
+			},
+		},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	handler := route.NewHandlers(routes)[0].Handler()
+	serve := func(token string) int {
+		req := httptest.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	for idx := range 3 {
+		if status := serve("guessed-token"); status != http.StatusUnauthorized {
+			t.Fatalf("expected status 401 for bad credentials [%d] but got %d", idx, status)
+		}
+	}
+
+	// Rejected credentials consumed the limit so further guesses and even valid credentials are limited
+	if status := serve("guessed-token"); status != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 for repeated bad credentials but got %d", status)
+	}
+	if status := serve("user-token"); status != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 for valid credentials after repeated bad credentials but got %d", status)
+	}
+}
+
+func TestHandler_IPAccess(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{
+		Server: config.Server{
+			TrustedProxies:  []string{"10.0.0.1"},
+			ForwardedHeader: "forwarded",
+			IPAccess: config.IPAccess{
+				Deny: []string{"203.0.113.0/24"},
+			},
+		},
+		Routes: []config.Route{
+			{
+				Path: "/admin",
+				IPAccess: config.IPAccess{
+					Allow:  []string{"198.51.100.0/24", "2001:db8::/32"},
+					Deny:   []string{"198.51.100.13"},
+					Denied: config.IPAccessDenied{Message: "admin is only available over VPN"},
+				},
+				Redirect: config.RouteRedirect{URL: "https://admin.example.com"},
+			},
+			{
+				Path: "/public",
+				IPAccess: config.IPAccess{
+					Deny:   []string{"192.0.2.0/24"},
+					Denied: config.IPAccessDenied{URL: "https://example.com/blocked"},
+				},
+				Redirect: config.RouteRedirect{URL: "https://public.example.com"},
+			},
+		},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for _, handler := range route.NewHandlers(routes) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	tests := []struct {
+		description   string
+		path          string
+		remoteAddr    string
+		forwarded     string
+		checkResponse func(*httptest.ResponseRecorder) error
+	}{
+		{
+			description:   "allows address in route allow list",
+			path:          "/admin",
+			remoteAddr:    "198.51.100.7:1234",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://admin.example.com"),
+		},
+		{
+			description:   "allows address in route allow list over ipv6",
+			path:          "/admin",
+			remoteAddr:    "[2001:db8::7]:1234",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://admin.example.com"),
+		},
+		{
+			description:   "denies address not in route allow list",
+			path:          "/admin",
+			remoteAddr:    "192.0.2.7:1234",
+			checkResponse: createResponseChecker(http.StatusForbidden, "admin is only available over VPN"),
+		},
+		{
+			description:   "denies address in route deny list over allow list",
+			path:          "/admin",
+			remoteAddr:    "198.51.100.13:1234",
+			checkResponse: createResponseChecker(http.StatusForbidden, "admin is only available over VPN"),
+		},
+		{
+			description:   "denies address in server deny list",
+			path:          "/public",
+			remoteAddr:    "203.0.113.7:1234",
+			checkResponse: createResponseChecker(http.StatusForbidden, "forbidden"),
+		},
+		{
+			description:   "redirects denied address to denied url",
+			path:          "/public",
+			remoteAddr:    "192.0.2.7:1234",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://example.com/blocked"),
+		},
+		{
+			description:   "resolves address forwarded by trusted proxy",
+			path:          "/admin",
+			remoteAddr:    "10.0.0.1:1234",
+			forwarded:     "for=198.51.100.7",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://admin.example.com"),
+		},
+		{
+			description:   "ignores address forwarded by untrusted proxy",
+			path:          "/admin",
+			remoteAddr:    "192.0.2.7:1234",
+			forwarded:     "for=198.51.100.7",
+			checkResponse: createResponseChecker(http.StatusForbidden, "admin is only available over VPN"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", test.path, nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.forwarded != "" {
+				req.Header.Set("Forwarded", test.forwarded)
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if err := test.checkResponse(rec); err != nil {
+				t.Fatalf("unexpected response: %s", err)
+			}
+		})
+	}
+}
+
+func TestHandler_Groups(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{
+		Server: config.Server{TrustedProxies: []string{"192.0.2.1/32"}},
+		Authentication: map[string]config.AuthProvider{
+			"sso": {Header: config.AuthHeader{User: "X-Forwarded-User"}},
+		},
+		Groups: []config.RouteGroup{
+			{
+				Prefix:      "/jira/",
+				Environment: config.RouteEnvironment{Allowlist: []string{"JIRA_HOST"}},
+				Params: map[string]string{
+					"host": `{{.GetEnv "JIRA_HOST"}}`,
+				},
+				Checks: []config.RouteCheck{
+					{Expr: `host != ""`, Error: "jira host is not configured"},
+				},
+				Routes: []config.Route{
+					{
+						Path:    "/browse/{id}",
+						Aliases: []string{"/b/{id}"},
+						Params: map[string]string{
+							"id": `{{.GetPath "id"}}`,
+						},
+						Checks: []config.RouteCheck{
+							{Expr: `id.matches("^[A-Z]+-[0-9]+$")`, Error: "invalid issue {{.id}}"},
+						},
+						Redirect: config.RouteRedirect{URL: "https://{{.host}}/browse/{{.id}}"},
+						Tests: []config.RouteTest{
+							{
+								Request:  config.RouteTestRequest{URL: "/b/ABC-1", Environment: map[string]string{"JIRA_HOST": "jira.example.com"}},
+								Response: config.RouteTestResponse{URL: "https://jira.example.com/browse/ABC-1"},
+							},
+						},
+					},
+				},
+			},
+			{
+				Prefix:         "/admin",
+				Authentication: "sso",
+				Routes: []config.Route{
+					{
+						Path:     "/users",
+						Redirect: config.RouteRedirect{URL: "https://admin.example.com/users"},
+					},
+				},
+			},
+			{
+				Prefix:   "/internal",
+				IPAccess: config.IPAccess{Deny: []string{"192.0.2.1"}},
+				Routes: []config.Route{
+					{
+						Path:     "/wiki",
+						Redirect: config.RouteRedirect{URL: "https://wiki.example.com"},
+					},
+				},
+			},
+		},
+	}, route.NewEnvironment(map[string]string{"JIRA_HOST": "jira.example.com"}))
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	handlers := route.NewHandlers(routes)
+	if err := route.TestHandlers(context.Background(), routes, handlers); err != nil {
+		t.Fatalf("expected route tests with prefixed urls to pass but got error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for _, handler := range handlers {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	tests := []struct {
+		description   string
+		path          string
+		headers       map[string]string
+		checkResponse func(*httptest.ResponseRecorder) error
+	}{
+		{
+			description:   "serves member route under prefix",
+			path:          "/jira/browse/ABC-1",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://jira.example.com/browse/ABC-1"),
+		},
+		{
+			description:   "serves member route alias under prefix",
+			path:          "/jira/b/ABC-1",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://jira.example.com/browse/ABC-1"),
+		},
+		{
+			description:   "runs route checks after group checks",
+			path:          "/jira/browse/abc",
+			checkResponse: createResponseChecker(http.StatusBadRequest, "invalid issue abc"),
+		},
+		{
+			description:   "does not serve member route without prefix",
+			path:          "/browse/ABC-1",
+			checkResponse: createResponseChecker(http.StatusNotFound, "404 page not found"),
+		},
+		{
+			description:   "requires group authentication for member route",
+			path:          "/admin/users",
+			checkResponse: createResponseChecker(http.StatusUnauthorized, "authentication required"),
+		},
+		{
+			description:   "serves member route to requests authenticated with group provider",
+			path:          "/admin/users",
+			headers:       map[string]string{"X-Forwarded-User": "alice"},
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://admin.example.com/users"),
+		},
+		{
+			description:   "denies member route to addresses denied by group",
+			path:          "/internal/wiki",
+			checkResponse: createResponseChecker(http.StatusForbidden, "forbidden"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", test.path, nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if err := test.checkResponse(rec); err != nil {
+				t.Fatalf("unexpected response: %s", err)
+			}
+		})
+	}
+}
+
+func TestHandler_VarsAndTemplates(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{
+		Vars: map[string]string{
+			"jiraHost": "jira.example.com",
+			"project":  "MURL",
+		},
+		Templates: map[string]string{
+			"jiraBase": "https://{{.vars.jiraHost}}",
+			"issue":    `{{template "jiraBase" .}}/browse/{{.vars.project}}-{{.id}}`,
+		},
+		Routes: []config.Route{
+			{
+				Path: "/issue/{id}",
+				Params: map[string]string{
+					"id":      `{{.GetPath "id"}}`,
+					"project": `{{.GetVar "project"}}`,
+				},
+				Checks: []config.RouteCheck{
+					{Expr: `id.matches("^[0-9]+$")`, Error: `{{.id}} is not an issue of {{.vars.project}}`},
+					{Expr: `project == vars.project`, Error: "unexpected project"},
+				},
+				Redirect: config.RouteRedirect{URL: `{{template "issue" .}}`},
+			},
+		},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for _, handler := range route.NewHandlers(routes) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	tests := []struct {
+		description   string
+		path          string
+		checkResponse func(*httptest.ResponseRecorder) error
+	}{
+		{
+			description:   "exposes vars to named templates",
+			path:          "/issue/42",
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://jira.example.com/browse/MURL-42"),
+		},
+		{
+			description:   "exposes vars to check errors",
+			path:          "/issue/abc",
+			checkResponse: createResponseChecker(http.StatusBadRequest, "abc is not an issue of MURL"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", test.path, nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if err := test.checkResponse(rec); err != nil {
+				t.Fatalf("unexpected response: %s", err)
+			}
+		})
+	}
+}
+
+func TestHandler_ClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{
+		Server: config.Server{
+			TLS: config.ServerTLSConfig{ClientCA: "/path/to/ca", ClientAuth: "optional"},
+		},
+		Routes: []config.Route{
+			{
+				Path: "/internal",
+				Params: map[string]string{
+					"client": `{{.GetClientCert.CommonName}}`,
+				},
+				Checks: []config.RouteCheck{
+					{
+						Expr:  `tls.verified && "Example" in tls.organizations`,
+						Error: "client certificate {{.tls.subject}} is not allowed",
+					},
+				},
+				Redirect: config.RouteRedirect{URL: "https://internal.example.com/?client={{.client}}"},
+			},
+		},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for _, handler := range route.NewHandlers(routes) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	tests := []struct {
+		description   string
+		certificate   *x509.Certificate
+		checkResponse func(*httptest.ResponseRecorder) error
+	}{
+		{
+			description:   "rejects requests without client certificate",
+			checkResponse: createResponseChecker(http.StatusBadRequest, "client certificate  is not allowed"),
+		},
+		{
+			description:   "rejects requests with client certificate of other organization",
+			certificate:   &x509.Certificate{Subject: pkix.Name{CommonName: "other", Organization: []string{"Other"}}},
+			checkResponse: createResponseChecker(http.StatusBadRequest, "client certificate CN=other,O=Other is not allowed"),
+		},
+		{
+			description:   "exposes client certificate to params",
+			certificate:   &x509.Certificate{Subject: pkix.Name{CommonName: "build-agent", Organization: []string{"Example"}}},
+			checkResponse: createResponseChecker(http.StatusTemporaryRedirect, "https://internal.example.com/?client=build-agent"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/internal", nil)
+			if test.certificate != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{test.certificate}}}
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if err := test.checkResponse(rec); err != nil {
+				t.Fatalf("unexpected response: %s", err)
+			}
+		})
+	}
+}
+
+func Test_TestHandlers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		routes      []config.Route
+		environment route.Environment
+	}{
+		{
+			description: "simple route with no params",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://example.com",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "complex params route",
+			routes: []config.Route{
+				{
+					Path: "/example/{id}",
+					Redirect: config.RouteRedirect{
+						URL: "https://{{.host}}/id/{{.id}}?a={{.query}}&b={{.header}}",
+					},
+					Environment: config.RouteEnvironment{
+						Allowlist: []string{"TEST_ENV_VAR"},
+					},
+					Params: map[string]string{
+						"id":     `{{.GetPath "id"}}`,
+						"query":  `{{.GetQuery "q"}}`,
+						"host":   `{{.GetEnv "TEST_ENV_VAR"}}`,
+						"header": `{{.GetHeader "x-test-header"}}`,
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example/wasd?q=xyz",
+								Headers: map[string]string{
+									"x-test-header": "abc",
+								},
+								Environment: map[string]string{
+									"TEST_ENV_VAR": "test.local",
+								},
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://test.local/id/wasd?a=xyz&b=abc",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "route tests with pinned time",
+			routes: []config.Route{
+				{
+					Path:        "/example",
+					ActiveFrom:  "2024-06-01T09:00:00+02:00",
+					ActiveUntil: "2024-06-01T10:00:00+02:00",
+					Inactive: config.RouteInactive{
+						URL: "https://example.com/ended",
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com/all-hands",
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL:  "/example",
+								Time: "2024-06-01T07:30:00Z",
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://example.com/all-hands",
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL:  "/example",
+								Time: "2024-06-01T08:00:00Z",
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://example.com/ended",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "route tests with pinned bucket and sticky header",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Redirect: config.RouteRedirect{
+						Targets: []config.RouteRedirectTarget{
+							{Name: "old", URL: "https://old.example.com", Weight: 90},
+							{Name: "new", URL: "https://new.example.com", Weight: 10},
+						},
+						Sticky: config.RouteRedirectSticky{Header: "x-user"},
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL:    "/example",
+								Bucket: func() *int { bucket := 89; return &bucket }(),
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://old.example.com",
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL:    "/example",
+								Bucket: func() *int { bucket := 90; return &bucket }(),
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://new.example.com",
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+								Headers: map[string]string{
+									"x-user": "alice",
+								},
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://old.example.com",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "route tests with simulated client address",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					IPAccess: config.IPAccess{
+						Allow:  []string{"198.51.100.0/24"},
+						Denied: config.IPAccessDenied{URL: "https://example.com/vpn"},
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://admin.example.com",
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL:        "/example",
+								RemoteAddr: "198.51.100.7",
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://admin.example.com",
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://example.com/vpn",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "route tests with negotiated language",
+			routes: []config.Route{
+				{
+					Path:      "/example",
+					Languages: []string{"en", "de"},
+					Params: map[string]string{
+						"lang": `{{.GetLanguage}}`,
+					},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com/{{.lang}}",
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+								Headers: map[string]string{
+									"Accept-Language": "de-AT, en;q=0.8",
+								},
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://example.com/de",
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+								Headers: map[string]string{
+									"Accept-Language": "sv",
+								},
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://example.com/en",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "route tests with rate limit",
+			routes: []config.Route{
+				{
+					Path:      "/example",
+					RateLimit: config.RateLimit{Requests: 5, Period: "1m", Burst: 2},
+					Redirect: config.RouteRedirect{
+						URL: "https://example.com",
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+							},
+							Response: config.RouteTestResponse{
+								URL:              "https://example.com",
+								RateLimitedAfter: 2,
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://example.com",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "test environment takes precedence over the snapshot",
+			routes: []config.Route{
+				{
+					Path: "/example",
+					Redirect: config.RouteRedirect{
+						URL: "https://{{.host}}/{{.other}}",
+					},
+					Environment: config.RouteEnvironment{
+						Allowlist: []string{"TEST_ENV_HOST", "TEST_ENV_OTHER"},
+					},
+					Params: map[string]string{
+						"host":  `{{.GetEnv "TEST_ENV_HOST"}}`,
+						"other": `{{.GetEnv "TEST_ENV_OTHER"}}`,
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{
+								URL: "/example",
+								Environment: map[string]string{
+									"TEST_ENV_HOST": "test.local",
+								},
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://test.local/snapshot",
+							},
+						},
+					},
+				},
+			},
+			environment: route.NewEnvironment(map[string]string{
+				"TEST_ENV_HOST":  "snapshot.local",
+				"TEST_ENV_OTHER": "snapshot",
+			}),
+		},
+		{
+			description: "route tests with status, body, header and method assertions",
+			routes: []config.Route{
+				{
+					Path:    "/issue/{id}",
+					Aliases: []string{"/i/{id}"},
+					Deprecations: []config.RouteDeprecation{
+						{Path: "/i/{id}", Sunset: "2030-01-01T00:00:00Z"},
+					},
+					Params: map[string]string{
+						"id": `{{.GetPath "id"}}`,
+					},
+					Checks: []config.RouteCheck{
+						{Expr: `id.matches("^[0-9]+$")`, Error: "issue {{.id}} must be numeric"},
+					},
+					Redirect: config.RouteRedirect{
+						Targets: []config.RouteRedirectTarget{
+							{Name: "old", URL: "https://old.example.com/{{.id}}", Weight: 1},
+							{Name: "new", URL: "https://new.example.com/{{.id}}", Weight: 1},
+						},
+						Sticky: config.RouteRedirectSticky{Cookie: "variant"},
+					},
+					Tests: []config.RouteTest{
+						{
+							Request: config.RouteTestRequest{URL: "/issue/abc"},
+							Response: config.RouteTestResponse{
+								Status: http.StatusBadRequest,
+								Body:   "must be numeric",
+							},
+						},
+						{
+							Request: config.RouteTestRequest{URL: "/issue/abc"},
+							Response: config.RouteTestResponse{
+								NoRedirect: true,
+								BodyRegex:  "^issue [a-z]+ must",
+							},
+						},
+						{
+							Request: config.RouteTestRequest{URL: "/issue/1", Method: "post"},
+							Response: config.RouteTestResponse{
+								Status:  http.StatusMethodNotAllowed,
+								Headers: map[string]string{"Allow": "GET, HEAD"},
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL:     "/i/1",
+								Cookies: map[string]string{"variant": "new"},
+							},
+							Response: config.RouteTestResponse{
+								URL: "https://new.example.com/1",
+								Headers: map[string]string{
+									"Deprecation": "true",
+									"Sunset":      "Tue, 01 Jan 2030 00:00:00 GMT",
+								},
+							},
+						},
+						{
+							Request: config.RouteTestRequest{
+								URL:  "/i/1",
+								Time: "2031-01-01T00:00:00Z",
+							},
+							Response: config.RouteTestResponse{
+								Status: http.StatusGone,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second)
 			defer cancelCtx()
 
-			routes, err := route.NewRoutes(test.routes)
+			routes, err := route.NewRoutes(config.Config{Routes: test.routes}, test.environment)
+			if err != nil {
+				t.Fatalf("failed to create test routes: %v", err)
+			}
+
+			handlers := route.NewHandlers(routes)
+			if err := route.TestHandlers(ctx, routes, handlers); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_TestHandlers_Failures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description   string
+		response      config.RouteTestResponse
+		expectedError error
+	}{
+		{
+			description:   "fails with unexpected status",
+			response:      config.RouteTestResponse{Status: http.StatusOK},
+			expectedError: errors.New("expected status 200 but got 400"),
+		},
+		{
+			description:   "fails with unexpected redirect",
+			response:      config.RouteTestResponse{URL: "https://example.com/abc"},
+			expectedError: errors.New("expected status 307 but got 400"),
+		},
+		{
+			description:   "fails with missing body substring",
+			response:      config.RouteTestResponse{NoRedirect: true, Body: "not found"},
+			expectedError: errors.New("expected body to contain \"not found\" but got \"issue abc must be numeric\\n\""),
+		},
+		{
+			description:   "fails with mismatching body regex",
+			response:      config.RouteTestResponse{NoRedirect: true, BodyRegex: "^[0-9]+$"},
+			expectedError: errors.New("expected body to match \"^[0-9]+$\" but got \"issue abc must be numeric\\n\""),
+		},
+		{
+			description:   "fails with mismatching header",
+			response:      config.RouteTestResponse{Status: http.StatusBadRequest, Headers: map[string]string{"Content-Type": "text/html"}},
+			expectedError: errors.New("expected header \"Content-Type\" to be \"text/html\" but got \"text/plain; charset=utf-8\""),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+				{
+					Path: "/issue/{id}",
+					Params: map[string]string{
+						"id": `{{.GetPath "id"}}`,
+					},
+					Checks: []config.RouteCheck{
+						{Expr: `id.matches("^[0-9]+$")`, Error: "issue {{.id}} must be numeric"},
+					},
+					Redirect: config.RouteRedirect{URL: "https://example.com/{{.id}}"},
+					Tests: []config.RouteTest{
+						{
+							Request:  config.RouteTestRequest{URL: "/issue/abc"},
+							Response: test.response,
+						},
+					},
+				},
+			}}, route.Environment{})
+			if err != nil {
+				t.Fatalf("failed to create test routes: %v", err)
+			}
+
+			expectedError := fmt.Errorf("test [0] for route \"/issue/{id}\" at index [0] failed: %w", test.expectedError)
+			err = route.TestHandlers(context.Background(), routes, route.NewHandlers(routes))
+			if err == nil || err.Error() != expectedError.Error() {
+				t.Fatalf("expected error %q but got %v", expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		{
+			Path:          "/issue/{id}",
+			Documentation: config.RouteDocumentation{Title: "Issue"},
+			Params: map[string]string{
+				"id": `{{.GetPath "id"}}`,
+			},
+			Redirect: config.RouteRedirect{URL: "https://example.com/{{.id}}"},
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1"},
+					Response: config.RouteTestResponse{URL: "https://example.com/2"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1"},
+					Response: config.RouteTestResponse{URL: "https://example.com/1"},
+				},
+			},
+		},
+		{
+			Path:     "/other",
+			Redirect: config.RouteRedirect{URL: "https://example.com/other"},
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/other", Method: "HEAD"},
+					Response: config.RouteTestResponse{Status: http.StatusOK},
+				},
+			},
+		},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	results := route.RunTests(context.Background(), routes, route.NewHandlers(routes))
+	if len(results) != 3 {
+		t.Fatalf("expected every test to run but got %d results", len(results))
+	}
+
+	expected := []route.TestResult{
+		{
+			RouteIndex: 0, Path: "/issue/{id}", Title: "Issue", TestIndex: 0, Method: "GET", URL: "/issue/1",
+			Failure: &route.TestFailure{
+				Message:  "expected redirect to \"https://example.com/2\" but got \"https://example.com/1\"",
+				Expected: "https://example.com/2",
+				Actual:   "https://example.com/1",
+			},
+		},
+		{RouteIndex: 0, Path: "/issue/{id}", Title: "Issue", TestIndex: 1, Method: "GET", URL: "/issue/1"},
+		{
+			RouteIndex: 1, Path: "/other", TestIndex: 0, Method: "HEAD", URL: "/other",
+			Failure: &route.TestFailure{
+				Message:  "expected status 200 but got 307",
+				Expected: "200",
+				Actual:   "307",
+			},
+		},
+	}
+	for idx, result := range results {
+		if result.Duration <= 0 {
+			t.Fatalf("expected result [%d] to have a duration", idx)
+		}
+		result.Duration = 0
+		if !reflect.DeepEqual(result, expected[idx]) {
+			t.Fatalf("expected result [%d] to be %+v but got %+v", idx, expected[idx], result)
+		}
+	}
+
+	if !results[1].Passed() || results[0].Passed() {
+		t.Fatalf("expected only the second test to pass")
+	}
+}
+
+func TestRunTestsWithCoverage(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		{
+			Path:          "/issue/{id}",
+			Aliases:       []string{"/i/{id}"},
+			Documentation: config.RouteDocumentation{Title: "Issue"},
+			Params: map[string]string{
+				"id":    `{{.GetPath "id"}}`,
+				"query": `{{.GetQuery "q"}}`,
+			},
+			Checks: []config.RouteCheck{
+				{Expr: `id.matches("^[0-9]+$")`, Error: "id must be numeric"},
+			},
+			Redirect: config.RouteRedirect{URL: "https://example.com/{{.id}}"},
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1"},
+					Response: config.RouteTestResponse{URL: "https://example.com/1"},
+				},
+			},
+		},
+		{
+			Path:     "/other",
+			Redirect: config.RouteRedirect{URL: "https://example.com/other"},
+		},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	results, coverage := route.RunTestsWithCoverage(context.Background(), routes, route.NewHandlers(routes))
+	if len(results) != 1 || !results[0].Passed() {
+		t.Fatalf("expected the single test to pass but got %+v", results)
+	}
+
+	expected := []route.RouteCoverage{
+		{
+			RouteIndex: 0,
+			Path:       "/issue/{id}",
+			Title:      "Issue",
+			Items: []route.CoverageItem{
+				{Name: `path "/issue/{id}"`, Covered: true},
+				{Name: `path "/i/{id}"`},
+				{Name: `param "id"`, Covered: true},
+				{Name: `param "query"`},
+				{Name: "check [0] passed", Covered: true},
+				{Name: "check [0] failed"},
+				{Name: "redirect url", Covered: true},
+			},
+		},
+		{
+			RouteIndex: 1,
+			Path:       "/other",
+			Items: []route.CoverageItem{
+				{Name: `path "/other"`},
+				{Name: "redirect url"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(coverage, expected) {
+		t.Fatalf("expected coverage %+v but got %+v", expected, coverage)
+	}
+
+	if coverage[0].Covered() != 4 {
+		t.Fatalf("expected 4 covered items but got %d", coverage[0].Covered())
+	}
+}
+
+func TestRunMutations(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		{
+			Path: "/issue/{id}",
+			Params: map[string]string{
+				"id": `{{.GetPath "id"}}`,
+			},
+			Checks: []config.RouteCheck{
+				{Expr: `size(id) > 2 && id != "999"`, Error: "invalid id"},
+			},
+			Redirect: config.RouteRedirect{URL: "https://example.com/{{.id}}"},
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/123"},
+					Response: config.RouteTestResponse{URL: "https://example.com/123"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1"},
+					Response: config.RouteTestResponse{Status: http.StatusBadRequest, Body: "invalid id"},
+				},
+			},
+		},
+		{
+			Path:     "/other",
+			Redirect: config.RouteRedirect{URL: "https://example.com/other{{if false}}unused{{end}}"},
+		},
+		{
+			Path:     "/broken",
+			Redirect: config.RouteRedirect{URL: "https://example.com/broken"},
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/broken"},
+					Response: config.RouteTestResponse{URL: "https://example.com/fixed"},
+				},
+			},
+		},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	expected := []route.Mutant{
+		{RouteIndex: 0, Path: "/issue/{id}", Description: "negate check [0]", Killed: true},
+		{RouteIndex: 0, Path: "/issue/{id}", Description: "remove check [0]", Killed: true},
+		{RouteIndex: 0, Path: "/issue/{id}", Description: `replace ">" with "<=" at position 9 in check [0]`, Killed: true},
+		{RouteIndex: 0, Path: "/issue/{id}", Description: `replace "!=" with "==" at position 19 in check [0]`, Killed: true},
+		{RouteIndex: 0, Path: "/issue/{id}", Description: `remove literal "https://example.com/" from redirect url`, Killed: true},
+		{RouteIndex: 1, Path: "/other", Description: `remove literal "https://example.com/other" from redirect url`},
+		{RouteIndex: 1, Path: "/other", Description: `remove literal "unused" from redirect url`},
+		{RouteIndex: 2, Path: "/broken", Description: `remove literal "https://example.com/broken" from redirect url`, Skipped: true},
+	}
+
+	mutants := route.RunMutations(context.Background(), routes)
+	if !reflect.DeepEqual(mutants, expected) {
+		t.Fatalf("expected mutants %+v but got %+v", expected, mutants)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		{
+			Path:          "/issue/{id}",
+			Aliases:       []string{"/i/{id}"},
+			Documentation: config.RouteDocumentation{Title: "Issue"},
+			Environment:   config.RouteEnvironment{Allowlist: []string{"HOST"}},
+			Params: map[string]string{
+				"id":   `{{.GetPath "id"}}`,
+				"host": `{{.GetEnv "HOST"}}`,
+				"lang": `{{.GetHeader "X-Lang"}}`,
+			},
+			Checks: []config.RouteCheck{
+				{Expr: `host != ""`, Error: "host is required"},
+				{Expr: `id.matches("^[0-9]+$")`, Error: "id must be numeric"},
+			},
+			Redirect: config.RouteRedirect{URL: "https://{{.host}}/{{.lang}}/{{.id}}"},
+		},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	matched := &route.TraceRoute{Index: 0, Path: "/issue/{id}", Title: "Issue", Pattern: "/i/{id}"}
+	tests := []struct {
+		description   string
+		request       route.ResolveRequest
+		expectedTrace route.Trace
+		expectedError error
+	}{
+		{
+			description: "traces redirect",
+			request: route.ResolveRequest{
+				URL:         "/i/1",
+				Headers:     map[string]string{"X-Lang": "en"},
+				Environment: route.NewEnvironment(map[string]string{"HOST": "example.com"}),
+			},
+			expectedTrace: route.Trace{
+				Method: "GET",
+				URL:    "/i/1",
+				Route:  matched,
+				Params: []route.TraceParam{
+					{Name: "host", Sources: []string{`env "HOST"`}, Template: `{{.GetEnv "HOST"}}`, Value: "example.com"},
+					{Name: "id", Sources: []string{`path "id"`}, Template: `{{.GetPath "id"}}`, Value: "1"},
+					{Name: "lang", Sources: []string{`header "X-Lang"`}, Template: `{{.GetHeader "X-Lang"}}`, Value: "en"},
+				},
+				Checks: []route.TraceCheck{
+					{Index: 0, Expr: `host != ""`, Passed: true},
+					{Index: 1, Expr: `id.matches("^[0-9]+$")`, Passed: true},
+				},
+				Status:   http.StatusTemporaryRedirect,
+				Location: "https://example.com/en/1",
+				Body:     `<a href="https://example.com/en/1">Temporary Redirect</a>.`,
+			},
+		},
+		{
+			description: "traces failed check",
+			request: route.ResolveRequest{
+				URL:         "/i/abc",
+				Environment: route.NewEnvironment(map[string]string{"HOST": "example.com"}),
+			},
+			expectedTrace: route.Trace{
+				Method: "GET",
+				URL:    "/i/abc",
+				Route:  matched,
+				Params: []route.TraceParam{
+					{Name: "host", Sources: []string{`env "HOST"`}, Template: `{{.GetEnv "HOST"}}`, Value: "example.com"},
+					{Name: "id", Sources: []string{`path "id"`}, Template: `{{.GetPath "id"}}`, Value: "abc"},
+					{Name: "lang", Sources: []string{`header "X-Lang"`}, Template: `{{.GetHeader "X-Lang"}}`, Value: ""},
+				},
+				Checks: []route.TraceCheck{
+					{Index: 0, Expr: `host != ""`, Passed: true},
+					{Index: 1, Expr: `id.matches("^[0-9]+$")`, Passed: false},
+				},
+				Status: http.StatusBadRequest,
+				Body:   "id must be numeric",
+			},
+		},
+		{
+			description: "traces unmatched request",
+			request:     route.ResolveRequest{URL: "/unknown"},
+			expectedTrace: route.Trace{
+				Method: "GET",
+				URL:    "/unknown",
+				Status: http.StatusNotFound,
+				Body:   "404 page not found",
+			},
+		},
+		{
+			description:   "fails with relative url",
+			request:       route.ResolveRequest{URL: "i/1"},
+			expectedError: errors.New(`url "i/1" must be an absolute path (start with slash)`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			trace, err := route.Resolve(context.Background(), routes, test.request)
+			if test.expectedError != nil {
+				if err == nil || err.Error() != test.expectedError.Error() {
+					t.Fatalf("expected error %q but got %v", test.expectedError, err)
+				}
+				return
+			}
 			if err != nil {
-				t.Fatalf("failed to create test routes: %v", err)
+				t.Fatalf("expected resolve to succeed but got error: %v", err)
 			}
 
-			handlers := route.NewHandlers(routes)
-			if err := route.TestHandlers(ctx, routes, handlers); err != nil {
-				t.Fatalf("unexpected error: %v", err)
+			if !reflect.DeepEqual(trace, test.expectedTrace) {
+				t.Fatalf("expected trace %+v but got %+v", test.expectedTrace, trace)
+			}
+		})
+	}
+}
+
+func TestResolve_ParamSources(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{
+		Vars: map[string]string{"team": "core"},
+		Routes: []config.Route{{
+			Path: "/search",
+			Params: map[string]string{
+				"combined": `{{.GetQuery "q"}}-{{.GetHeader "X-Lang"}}-{{.GetQuery "q"}}`,
+				"constant": "fixed",
+				"team":     `{{.GetVar "team"}}`,
+			},
+			Redirect: config.RouteRedirect{URL: "https://example.com/?q={{.combined}}&c={{.constant}}&t={{.team}}"},
+		}},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	trace, err := route.Resolve(context.Background(), routes, route.ResolveRequest{
+		URL:     "/search?q=go",
+		Headers: map[string]string{"X-Lang": "en"},
+	})
+	if err != nil {
+		t.Fatalf("expected resolve to succeed but got error: %v", err)
+	}
+
+	expected := []route.TraceParam{
+		{Name: "combined", Sources: []string{`query "q"`, `header "X-Lang"`}, Template: `{{.GetQuery "q"}}-{{.GetHeader "X-Lang"}}-{{.GetQuery "q"}}`, Value: "go-en-go"},
+		{Name: "constant", Template: "fixed", Value: "fixed"},
+		{Name: "team", Sources: []string{`var "team"`}, Template: `{{.GetVar "team"}}`, Value: "core"},
+	}
+	if !reflect.DeepEqual(trace.Params, expected) {
+		t.Fatalf("expected params %+v but got %+v", expected, trace.Params)
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	t.Parallel()
+
+	issue := config.Route{
+		Path:   "/issue/{id}",
+		Params: map[string]string{"id": `{{.GetPath "id"}}`},
+		Checks: []config.RouteCheck{
+			{Expr: `id != "0"`, Error: "invalid id"},
+		},
+		Redirect: config.RouteRedirect{URL: "https://example.com/{{.id}}"},
+		Tests: []config.RouteTest{
+			{
+				Request:  config.RouteTestRequest{URL: "/issue/1"},
+				Response: config.RouteTestResponse{URL: "https://example.com/1"},
+			},
+		},
+	}
+	oldRoutes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		issue,
+		{Path: "/old", Redirect: config.RouteRedirect{URL: "https://example.com/old"}},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create old routes: %v", err)
+	}
+
+	changedIssue := issue
+	changedIssue.Checks = []config.RouteCheck{
+		{Expr: `id != "0" && id != "2"`, Error: "invalid id"},
+	}
+	changedIssue.Tests = append([]config.RouteTest{}, issue.Tests...)
+	changedIssue.Tests = append(changedIssue.Tests, config.RouteTest{
+		Request:  config.RouteTestRequest{URL: "/issue/2"},
+		Response: config.RouteTestResponse{Status: http.StatusBadRequest, Body: "invalid id"},
+	})
+	newRoutes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		changedIssue,
+		{Path: "/new", Redirect: config.RouteRedirect{URL: "https://example.com/new"}},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create new routes: %v", err)
+	}
+
+	diff, err := route.DiffRoutes(context.Background(), oldRoutes, newRoutes, []string{"/issue/1", "/old", "/issue/0"})
+	if err != nil {
+		t.Fatalf("expected diff to succeed but got error: %v", err)
+	}
+
+	expected := route.Diff{
+		AddedRoutes:   []string{"/new"},
+		RemovedRoutes: []string{"/old"},
+		Requests:      4,
+		Changes: []route.DiffChange{
+			{
+				Method: "GET",
+				URL:    "/issue/2",
+				Old:    route.DiffResponse{Route: "/issue/{id}", Status: http.StatusTemporaryRedirect, Location: "https://example.com/2"},
+				New:    route.DiffResponse{Route: "/issue/{id}", Status: http.StatusBadRequest, FailedCheck: `id != "0" && id != "2"`},
+			},
+			{
+				Method: "GET",
+				URL:    "/old",
+				Old:    route.DiffResponse{Route: "/old", Status: http.StatusTemporaryRedirect, Location: "https://example.com/old"},
+				New:    route.DiffResponse{Status: http.StatusNotFound},
+			},
+			{
+				Method: "GET",
+				URL:    "/issue/0",
+				Old:    route.DiffResponse{Route: "/issue/{id}", Status: http.StatusBadRequest, FailedCheck: `id != "0"`},
+				New:    route.DiffResponse{Route: "/issue/{id}", Status: http.StatusBadRequest, FailedCheck: `id != "0" && id != "2"`},
+			},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected diff %+v but got %+v", expected, diff)
+	}
+
+	if !diff.Changes[0].NewlyFailingCheck() || diff.Changes[1].NewlyFailingCheck() || diff.Changes[2].NewlyFailingCheck() {
+		t.Fatalf("expected only the first change to be a newly failing check")
+	}
+
+	if _, err := route.DiffRoutes(context.Background(), oldRoutes, newRoutes, []string{"issue/1"}); err == nil {
+		t.Fatalf("expected diff to fail with relative url")
+	}
+}
+
+func TestFuzz(t *testing.T) {
+	t.Parallel()
+
+	conf := config.Config{Routes: []config.Route{
+		{
+			Path:     "/raw/{id}",
+			Params:   map[string]string{"id": `{{.GetPath "id"}}`},
+			Redirect: config.RouteRedirect{URL: "https://example.com/{{.id}}"},
+		},
+		{
+			Path:     "/escaped/{id}",
+			Params:   map[string]string{"id": `{{.GetPath "id"}}`},
+			Redirect: config.RouteRedirect{URL: "https://example.com/{{.id | urlquery}}"},
+		},
+		{
+			Path:     "/prefix",
+			Params:   map[string]string{"prefix": `{{slice (.GetQuery "q") 0 2}}`},
+			Redirect: config.RouteRedirect{URL: "https://example.com/{{.prefix | urlquery}}"},
+		},
+	}}
+	routes, err := route.NewRoutes(conf, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	failures := route.Fuzz(context.Background(), routes, 200, 1)
+	if !reflect.DeepEqual(failures, route.Fuzz(context.Background(), routes, 200, 1)) {
+		t.Fatalf("expected fuzzing with the same seed to generate the same failures")
+	}
+
+	messages := map[int][]string{}
+	for _, failure := range failures {
+		messages[failure.RouteIndex] = append(messages[failure.RouteIndex], failure.Message)
+	}
+	if !slices.Contains(messages[0], "redirect url contains CR or LF") {
+		t.Fatalf("expected CR or LF in the redirect url of the raw route to be found but got %v", messages[0])
+	}
+	if len(messages[1]) != 0 {
+		t.Fatalf("expected no failures for the escaped route but got %v", messages[1])
+	}
+	if len(messages[2]) != 1 || !strings.HasPrefix(messages[2][0], "template execution failed: ") {
+		t.Fatalf("expected a template execution failure for the prefix route but got %v", messages[2])
+	}
+
+	// The reproducing tests fail until the routes are fixed
+	for _, failure := range failures {
+		conf.Routes[failure.RouteIndex].Tests = append(conf.Routes[failure.RouteIndex].Tests, failure.Test)
+	}
+	routes, err = route.NewRoutes(conf, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create routes with reproducing tests: %v", err)
+	}
+	reproduced := map[int]bool{}
+	for _, result := range route.RunTests(context.Background(), routes, route.NewHandlers(routes)) {
+		if result.Passed() {
+			t.Fatalf("expected %s to reproduce the failure", result.Name())
+		}
+		reproduced[result.RouteIndex] = true
+	}
+	// The template execution failure is already responded to with 400 Bad Request but is reproduced nonetheless
+	if !reproduced[0] || !reproduced[2] {
+		t.Fatalf("expected the failures of the raw and prefix routes to be reproduced but got %v", reproduced)
+	}
+}
+
+func TestDestinations(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		{
+			Path:     "/issue/{id}",
+			Params:   map[string]string{"id": `{{.GetPath "id"}}`},
+			Redirect: config.RouteRedirect{URL: "https://example.com/{{.id}}"},
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1"},
+					Response: config.RouteTestResponse{URL: "https://example.com/1"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/2"},
+					Response: config.RouteTestResponse{Status: http.StatusOK, NoRedirect: true},
+				},
+			},
+		},
+		{
+			Path:          "/docs",
+			Documentation: config.RouteDocumentation{Title: "Docs"},
+			Redirect: config.RouteRedirect{Targets: []config.RouteRedirectTarget{
+				{Name: "old", URL: "https://old.example.com/docs", Weight: 0},
+				{Name: "new", URL: "https://new.example.com/docs", Weight: 1},
+			}},
+		},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	expected := []route.Destination{
+		{RouteIndex: 0, Path: "/issue/{id}", Source: "test [0]", URL: "https://example.com/1"},
+		{RouteIndex: 1, Path: "/docs", Title: "Docs", Source: `redirect target "new"`, URL: "https://new.example.com/docs"},
+	}
+	if destinations := route.Destinations(routes); !reflect.DeepEqual(destinations, expected) {
+		t.Fatalf("expected destinations %+v but got %+v", expected, destinations)
+	}
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	lintRoute := config.Route{
+		Path: "/issue/{id}/{rest...}",
+		Environment: config.RouteEnvironment{
+			Allowlist: []string{"HOST", "UNUSED_HOST"},
+		},
+		Params: map[string]string{
+			"host":   `{{.GetEnv "HOST"}}`,
+			"id":     `{{.GetPath "id"}}`,
+			"unused": `{{.GetQuery "unused"}}`,
+		},
+		Checks: []config.RouteCheck{
+			{Expr: `host != ""`, Error: " "},
+		},
+		Redirect: config.RouteRedirect{URL: "https://{{.host}}/{{.id}}"},
+	}
+
+	tests := []struct {
+		name     string
+		conf     config.Config
+		expected []string
+	}{
+		{
+			name: "all rules",
+			conf: config.Config{Routes: []config.Route{lintRoute}},
+			expected: []string{
+				`unused-env: environment variable "UNUSED_HOST" is allowlisted but never read with GetEnv`,
+				`unused-param: param "unused" is never used in checks, check errors or redirect templates`,
+				"missing-title: route has no documentation title and is hidden from the generated documentation",
+				"missing-tests: route has no tests",
+				`unused-wildcard: path wildcard "rest" of "/issue/{id}/{rest...}" is never read with GetPath`,
+				"blank-check-error: check [0] has a blank error message",
+			},
+		},
+		{
+			name: "clean route",
+			conf: config.Config{Routes: []config.Route{{
+				Path:          "/issue/{id}",
+				Documentation: config.RouteDocumentation{Title: "Issue"},
+				Params:        map[string]string{"id": `{{.GetPath "id"}}`},
+				Checks:        []config.RouteCheck{{Expr: `id != ""`, Error: "id {{.id}} is required"}},
+				Redirect:      config.RouteRedirect{URL: "https://example.com/{{template \"id\" .}}"},
+				Tests: []config.RouteTest{{
+					Request:  config.RouteTestRequest{URL: "/issue/1"},
+					Response: config.RouteTestResponse{URL: "https://example.com/1"},
+				}},
+			}}, Templates: map[string]string{"id": "{{.id}}"}},
+			expected: []string{},
+		},
+		{
+			name: "rules disabled globally and per route",
+			conf: config.Config{
+				Lint: config.Lint{Disable: []string{route.LintMissingTitle, route.LintMissingTests}},
+				Routes: []config.Route{func() config.Route {
+					r := lintRoute
+					r.Lint = config.Lint{Disable: []string{route.LintUnusedEnv, route.LintUnusedParam, route.LintUnusedWildcard}}
+					return r
+				}()},
+			},
+			expected: []string{
+				"blank-check-error: check [0] has a blank error message",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			findings, err := route.Lint(tt.conf)
+			if err != nil {
+				t.Fatalf("failed to lint routes: %v", err)
+			}
+
+			result := []string{}
+			for _, finding := range findings {
+				if finding.RouteIndex != 0 || finding.Path != tt.conf.Routes[0].Path {
+					t.Fatalf("expected finding of route at index [0] but got %+v", finding)
+				}
+				result = append(result, finding.Rule+": "+finding.Message)
+			}
+			if !slices.Equal(result, tt.expected) {
+				t.Fatalf("expected findings %q but got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLint_Failures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		conf     config.Config
+		expected string
+	}{
+		{
+			name:     "unknown global rule",
+			conf:     config.Config{Lint: config.Lint{Disable: []string{"unknown"}}, Routes: []config.Route{buildTestRoute(func(*config.Route) {})}},
+			expected: `failed to parse lint: unknown lint rule "unknown"`,
+		},
+		{
+			name: "unknown route rule",
+			conf: config.Config{Routes: []config.Route{buildTestRoute(func(route *config.Route) {
+				route.Lint.Disable = []string{"unknown"}
+			})}},
+			expected: `failed to parse lint for route at index [0]: unknown lint rule "unknown"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := route.Lint(tt.conf)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected error containing %q but got %v", tt.expected, err)
 			}
 		})
 	}
 }
+
+func TestRunRemoteTests(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{Routes: []config.Route{
+		{
+			Path: "/issue/{id}",
+			Environment: config.RouteEnvironment{
+				Allowlist: []string{"HOST"},
+			},
+			Params: map[string]string{
+				"id":   `{{.GetPath "id"}}`,
+				"host": `{{or (.GetEnv "HOST") "example.com"}}`,
+			},
+			Redirect: config.RouteRedirect{URL: "https://{{.host}}/{{.id}}"},
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1?x=1", Headers: map[string]string{"X-Test": "1"}},
+					Response: config.RouteTestResponse{URL: "https://example.com/1"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1"},
+					Response: config.RouteTestResponse{URL: "https://example.com/2"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1", Environment: map[string]string{"HOST": "localhost"}},
+					Response: config.RouteTestResponse{URL: "https://localhost/1"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/issue/1", Time: "2025-01-01T00:00:00Z"},
+					Response: config.RouteTestResponse{URL: "https://example.com/1"},
+				},
+			},
+		},
+	}}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	for _, handler := range route.NewHandlers(routes) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+	server := httptest.NewServer(http.StripPrefix("/links", mux))
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL + "/links/")
+	if err != nil {
+		t.Fatalf("failed to parse target: %v", err)
+	}
+
+	results := route.RunRemoteTests(context.Background(), routes, target, server.Client())
+
+	expected := []route.TestResult{
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 0, Method: "GET", URL: "/issue/1?x=1"},
+		{
+			RouteIndex: 0, Path: "/issue/{id}", TestIndex: 1, Method: "GET", URL: "/issue/1",
+			Failure: &route.TestFailure{
+				Message:  "expected redirect to \"https://example.com/2\" but got \"https://example.com/1\"",
+				Expected: "https://example.com/2",
+				Actual:   "https://example.com/1",
+			},
+		},
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 2, Method: "GET", URL: "/issue/1", Skipped: "test overrides the environment"},
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 3, Method: "GET", URL: "/issue/1", Skipped: "test pins the request time"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results but got %d", len(expected), len(results))
+	}
+	for idx, result := range results {
+		result.Duration = 0
+		if !reflect.DeepEqual(result, expected[idx]) {
+			t.Fatalf("expected result [%d] to be %+v but got %+v", idx, expected[idx], result)
+		}
+	}
+
+	server.Close()
+	results = route.RunRemoteTests(context.Background(), routes, target, server.Client())
+	if results[0].Failure == nil || !strings.HasPrefix(results[0].Failure.Message, "request failed: ") {
+		t.Fatalf("expected request to an unreachable target to fail but got %+v", results[0])
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	conf := config.Config{
+		Routes: []config.Route{
+			{
+				Path:          "/search",
+				Documentation: config.RouteDocumentation{Title: "Search"},
+				Params: map[string]string{
+					"q":     `{{.GetQuery "q"}}`,
+					"token": `{{.GetHeader "X-Token"}}`,
+				},
+				Checks:   []config.RouteCheck{{Expr: `q != ""`, Error: "q is required"}},
+				Redirect: config.RouteRedirect{URL: "https://example.com/?q={{.q}}&t={{.token}}"},
+			},
+			{
+				Path: "/split",
+				Redirect: config.RouteRedirect{
+					Targets: []config.RouteRedirectTarget{
+						{Name: "old", URL: "https://old.example.com", Weight: 3},
+						{Name: "new", URL: "https://new.example.com", Weight: 1},
+					},
+					Sticky: config.RouteRedirectSticky{Cookie: "variant"},
+				},
+			},
+		},
+		Groups: []config.RouteGroup{
+			{
+				Prefix: "/jira/",
+				Routes: []config.Route{{
+					Path:     "/browse/{id}",
+					Params:   map[string]string{"id": `{{.GetPath "id"}}`},
+					Redirect: config.RouteRedirect{URL: "https://jira.example.com/browse/{{.id}}"},
+				}},
+			},
+		},
+	}
+	routes, err := route.NewRoutes(conf, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	recorder := route.NewRecorder(routes, route.RecordOptions{Samples: 2, RedactHeaders: []string{"x-token"}})
+	mux := http.NewServeMux()
+	for _, handler := range recorder.Handlers(route.NewHandlers(routes)) {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	for _, request := range []struct {
+		url     string
+		headers map[string]string
+		cookies map[string]string
+	}{
+		{url: "/search?q=go&utm_source=mail", headers: map[string]string{"X-Token": "e", "User-Agent": "test"}},
+		{url: "/search?q=go&utm_source=chat", headers: map[string]string{"X-Token": "e"}},
+		{url: "/search"},
+		{url: "/search?q=dropped"},
+		{url: "/split", cookies: map[string]string{"variant": "new"}},
+		{url: "/split", cookies: map[string]string{"variant": "old"}},
+		{url: "/jira/browse/ABC-1"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, request.url, nil)
+		for key, value := range request.headers {
+			req.Header.Set(key, value)
+		}
+		for name, value := range request.cookies {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	expected := []route.RecordedTests{
+		{
+			RouteIndex: 0,
+			Path:       "/search",
+			Title:      "Search",
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/search?q=go", Headers: map[string]string{"X-Token": route.RedactedValue}},
+					Response: config.RouteTestResponse{URL: "https://example.com/?q=go&t=REDACTED"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/search"},
+					Response: config.RouteTestResponse{Status: http.StatusBadRequest},
+				},
+			},
+		},
+		{
+			RouteIndex: 1,
+			Path:       "/split",
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/split", Bucket: func() *int { bucket := 3; return &bucket }()},
+					Response: config.RouteTestResponse{URL: "https://new.example.com"},
+				},
+				{
+					Request:  config.RouteTestRequest{URL: "/split", Bucket: func() *int { bucket := 0; return &bucket }()},
+					Response: config.RouteTestResponse{URL: "https://old.example.com"},
+				},
+			},
+		},
+		{
+			RouteIndex: 2,
+			Path:       "/jira/browse/{id}",
+			Tests: []config.RouteTest{
+				{
+					Request:  config.RouteTestRequest{URL: "/browse/ABC-1"},
+					Response: config.RouteTestResponse{URL: "https://jira.example.com/browse/ABC-1"},
+				},
+			},
+		},
+	}
+	if recorded := recorder.Tests(); !reflect.DeepEqual(recorded, expected) {
+		t.Fatalf("expected recorded tests %+v but got %+v", expected, recorded)
+	}
+
+	// The recorded tests of the split route pin the bucket so they pass on every replay
+	conf.Routes[1].Tests = expected[1].Tests
+	routes, err = route.NewRoutes(conf, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create routes with recorded tests: %v", err)
+	}
+	for range 10 {
+		if err := route.TestHandlers(context.Background(), routes, route.NewHandlers(routes)); err != nil {
+			t.Fatalf("expected recorded tests to pass on replay but got %v", err)
+		}
+	}
+}
+
+func TestRunTests_ParameterizedAndFixtures(t *testing.T) {
+	t.Parallel()
+
+	routes, err := route.NewRoutes(config.Config{
+		TestFixtures: config.RouteTestFixtures{
+			Environment: map[string]string{"HOST": "global.example.com", "LANG": "en"},
+		},
+		Routes: []config.Route{
+			{
+				Path: "/issue/{id}",
+				Environment: config.RouteEnvironment{
+					Allowlist: []string{"HOST", "LANG"},
+				},
+				Params: map[string]string{
+					"id":    `{{.GetPath "id"}}`,
+					"host":  `{{.GetEnv "HOST"}}`,
+					"lang":  `{{.GetEnv "LANG"}}`,
+					"token": `{{.GetHeader "X-Token"}}`,
+				},
+				Redirect: config.RouteRedirect{URL: "https://{{.host}}/{{.lang}}/{{.id}}?t={{.token}}"},
+				TestFixtures: config.RouteTestFixtures{
+					Environment: map[string]string{"HOST": "route.example.com"},
+					Headers:     map[string]string{"X-Token": "fixture"},
+				},
+				Tests: []config.RouteTest{
+					{
+						Request:  config.RouteTestRequest{URL: "/issue/0", Headers: map[string]string{"X-Token": "own"}},
+						Response: config.RouteTestResponse{URL: "https://route.example.com/en/0?t=own"},
+					},
+					{
+						Request:  config.RouteTestRequest{URL: "/issue/{{.id}}", Environment: map[string]string{"LANG": "{{.lang}}"}},
+						Response: config.RouteTestResponse{URL: "https://route.example.com/{{.lang}}/{{.id}}?t=fixture"},
+						Rows: []map[string]string{
+							{"id": "1", "lang": "de"},
+							{"id": "2", "lang": "fi"},
+						},
+					},
+					{
+						Request:  config.RouteTestRequest{URL: "/issue/{{.id}}"},
+						Response: config.RouteTestResponse{URL: "https://route.example.com/en/{{.expected}}?t=fixture"},
+						Rows: []map[string]string{
+							{"id": "3", "expected": "4"},
+						},
+					},
+				},
+			},
+		},
+	}, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	results := route.RunTests(context.Background(), routes, route.NewHandlers(routes))
+
+	expected := []route.TestResult{
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 0, Method: "GET", URL: "/issue/0"},
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 1, Row: &route.TestRow{Index: 0, Values: map[string]string{"id": "1", "lang": "de"}}, Method: "GET", URL: "/issue/1"},
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 1, Row: &route.TestRow{Index: 1, Values: map[string]string{"id": "2", "lang": "fi"}}, Method: "GET", URL: "/issue/2"},
+		{
+			RouteIndex: 0, Path: "/issue/{id}", TestIndex: 2, Row: &route.TestRow{Index: 0, Values: map[string]string{"id": "3", "expected": "4"}}, Method: "GET", URL: "/issue/3",
+			Failure: &route.TestFailure{
+				Message:  "expected redirect to \"https://route.example.com/en/4?t=fixture\" but got \"https://route.example.com/en/3?t=fixture\"",
+				Expected: "https://route.example.com/en/4?t=fixture",
+				Actual:   "https://route.example.com/en/3?t=fixture",
+			},
+		},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results but got %d", len(expected), len(results))
+	}
+	for idx, result := range results {
+		result.Duration = 0
+		if !reflect.DeepEqual(result, expected[idx]) {
+			t.Fatalf("expected result [%d] to be %+v but got %+v", idx, expected[idx], result)
+		}
+	}
+
+	if name := results[3].Name(); name != `test [2] row [0] for route "/issue/{id}" at index [0]` {
+		t.Fatalf("expected the name to identify the row but got %q", name)
+	}
+}
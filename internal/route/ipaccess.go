@@ -0,0 +1,72 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/slightly-inconvenient/murl/internal/clientip"
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+type RouteIPAccess struct {
+	allow   []netip.Prefix
+	deny    []netip.Prefix
+	message string
+	url     string
+}
+
+// parseIPAccess parses the client address allow and deny lists. Returns nil if addresses are not restricted.
+func parseIPAccess(conf config.IPAccess) (*RouteIPAccess, error) {
+	if len(conf.Allow) == 0 && len(conf.Deny) == 0 {
+		if conf.Denied.Message != "" || conf.Denied.URL != "" {
+			return nil, fmt.Errorf("denied response requires an allow or deny list")
+		}
+		return nil, nil
+	}
+
+	allow, err := clientip.ParsePrefixes(conf.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse allow list: %w", err)
+	}
+
+	deny, err := clientip.ParsePrefixes(conf.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deny list: %w", err)
+	}
+
+	result := &RouteIPAccess{
+		allow:   allow,
+		deny:    deny,
+		message: "forbidden",
+		url:     conf.Denied.URL,
+	}
+	if conf.Denied.Message != "" {
+		result.message = conf.Denied.Message
+	}
+
+	return result, nil
+}
+
+// allowed reports whether the client address may use the route.
+// Unresolvable addresses are only allowed if there is no allow list.
+func (s *RouteIPAccess) allowed(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return len(s.allow) == 0
+	}
+
+	if clientip.Contains(s.deny, addr) {
+		return false
+	}
+
+	return len(s.allow) == 0 || clientip.Contains(s.allow, addr)
+}
+
+func (s *RouteIPAccess) writeDenied(w http.ResponseWriter, r *http.Request) {
+	if s.url != "" {
+		http.Redirect(w, r, s.url, http.StatusTemporaryRedirect)
+		return
+	}
+
+	http.Error(w, s.message, http.StatusForbidden)
+}
@@ -0,0 +1,77 @@
+package route
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"golang.org/x/text/language"
+)
+
+type RouteLanguages struct {
+	supported []string
+	matcher   language.Matcher
+}
+
+func parseRouteLanguages(supported []string) (RouteLanguages, error) {
+	if len(supported) == 0 {
+		return RouteLanguages{}, nil
+	}
+
+	tags := make([]language.Tag, 0, len(supported))
+	for _, value := range supported {
+		tag, err := language.Parse(value)
+		if err != nil {
+			return RouteLanguages{}, fmt.Errorf("failed to parse supported language %q: %w", value, err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return RouteLanguages{
+		supported: supported,
+		matcher:   language.NewMatcher(tags),
+	}, nil
+}
+
+// negotiate picks the best supported language for the Accept-Language header value.
+// Falls back to the first supported language if none of the requested languages match.
+func (s RouteLanguages) negotiate(acceptLanguage string) (string, error) {
+	if s.matcher == nil {
+		return "", fmt.Errorf("route has no supported languages configured")
+	}
+
+	// Malformed headers are handled by falling back to the default language as ParseAcceptLanguage returns no tags.
+	requested, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, idx, _ := s.matcher.Match(requested...)
+
+	return s.supported[idx], nil
+}
+
+// celOptions returns the CEL environment options for the language negotiation helper.
+// The helper is only available for routes with supported languages configured.
+func (s RouteLanguages) celOptions() []cel.EnvOption {
+	if s.matcher == nil {
+		return nil
+	}
+
+	return []cel.EnvOption{
+		cel.Function("language",
+			cel.Overload("language_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					acceptLanguage, ok := value.(types.String)
+					if !ok {
+						return types.MaybeNoSuchOverloadErr(value)
+					}
+
+					result, err := s.negotiate(string(acceptLanguage))
+					if err != nil {
+						return types.WrapErr(err)
+					}
+
+					return types.String(result)
+				}),
+			),
+		),
+	}
+}
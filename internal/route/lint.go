@@ -0,0 +1,184 @@
+package route
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template/parse"
+
+	"github.com/google/cel-go/common/ast"
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+const (
+	LintUnusedEnv       = "unused-env"
+	LintUnusedParam     = "unused-param"
+	LintMissingTitle    = "missing-title"
+	LintMissingTests    = "missing-tests"
+	LintUnusedWildcard  = "unused-wildcard"
+	LintBlankCheckError = "blank-check-error"
+)
+
+// LintRules are the names of all lint rules.
+var LintRules = []string{LintUnusedEnv, LintUnusedParam, LintMissingTitle, LintMissingTests, LintUnusedWildcard, LintBlankCheckError}
+
+// LintFinding is a hygiene issue of a route which does not prevent serving it.
+type LintFinding struct {
+	RouteIndex int
+	Path       string
+	Title      string
+	Rule       string
+	Message    string
+}
+
+// Lint validates the routes of the configuration like NewRoutes and returns the hygiene issues of every route in order:
+// allowlisted environment variables never read, params never used, routes without a documentation title or tests,
+// path wildcards never read and checks with blank error messages.
+// Rules disabled for all routes or the route are not reported.
+func Lint(conf config.Config) ([]LintFinding, error) {
+	routes, err := NewRoutes(conf, Environment{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Route configurations aligned with the validated routes
+	routeConfigs, err := expandRoutes(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkLintRules(conf.Lint.Disable); err != nil {
+		return nil, fmt.Errorf("failed to parse lint: %w", err)
+	}
+
+	result := []LintFinding{}
+	for idx, route := range routes {
+		routeConfig := routeConfigs[idx]
+		if err := checkLintRules(routeConfig.Lint.Disable); err != nil {
+			return nil, fmt.Errorf("failed to parse lint for route at index [%d]: %w", idx, err)
+		}
+		disabled := map[string]bool{}
+		for _, rule := range slices.Concat(conf.Lint.Disable, routeConfig.Lint.Disable) {
+			disabled[rule] = true
+		}
+
+		report := func(rule string, format string, args ...any) {
+			if !disabled[rule] {
+				result = append(result, LintFinding{
+					RouteIndex: idx,
+					Path:       route.paths[0],
+					Title:      route.title,
+					Rule:       rule,
+					Message:    fmt.Sprintf(format, args...),
+				})
+			}
+		}
+
+		reads := route.paramTemplateReads()
+		for _, key := range routeConfig.Environment.Allowlist {
+			if !reads["GetEnv"][key] {
+				report(LintUnusedEnv, "environment variable %q is allowlisted but never read with GetEnv", key)
+			}
+		}
+
+		used := route.usedParams(routeConfig)
+		for _, key := range slices.Sorted(maps.Keys(route.params)) {
+			if !used[key] {
+				report(LintUnusedParam, "param %q is never used in checks, check errors or redirect templates", key)
+			}
+		}
+
+		if route.title == "" {
+			report(LintMissingTitle, "route has no documentation title and is hidden from the generated documentation")
+		}
+
+		if len(route.tests) == 0 {
+			report(LintMissingTests, "route has no tests")
+		}
+
+		for _, path := range route.paths {
+			for _, match := range pathWildcardPattern.FindAllStringSubmatch(path, -1) {
+				name := strings.TrimSuffix(match[1], "...")
+				if name != "$" && !reads["GetPath"][name] {
+					report(LintUnusedWildcard, "path wildcard %q of %q is never read with GetPath", name, path)
+				}
+			}
+		}
+
+		for cidx, check := range routeConfig.Checks {
+			if strings.TrimSpace(check.Error) == "" {
+				report(LintBlankCheckError, "check [%d] has a blank error message", cidx)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func checkLintRules(rules []string) error {
+	for _, rule := range rules {
+		if !slices.Contains(LintRules, rule) {
+			return fmt.Errorf("unknown lint rule %q (supported are %s)", rule, strings.Join(LintRules, ", "))
+		}
+	}
+
+	return nil
+}
+
+// paramTemplateReads returns the arguments the param templates of the route call each request accessor with, e.g. the keys read with GetEnv.
+func (s Route) paramTemplateReads() map[string]map[string]bool {
+	result := map[string]map[string]bool{}
+	for _, method := range []string{"GetEnv", "GetPath"} {
+		result[method] = map[string]bool{}
+		for _, param := range s.params {
+			for _, arg := range templateCallArgs(param, method) {
+				result[method][arg] = true
+			}
+		}
+	}
+
+	return result
+}
+
+// usedParams returns the params the checks, check errors, redirect templates, sticky redirect or rate limit of the route reference.
+func (s Route) usedParams(routeConfig config.Route) map[string]bool {
+	result := map[string]bool{
+		routeConfig.Redirect.Sticky.Param: true,
+		routeConfig.RateLimit.Key.Param:   true,
+	}
+
+	for _, check := range s.checks {
+		parsed, issues := s.celEnv.Parse(check.source)
+		if issues != nil && issues.Err() != nil {
+			continue
+		}
+		for _, ident := range ast.MatchDescendants(ast.NavigateAST(parsed.NativeRep()), ast.KindMatcher(ast.IdentKind)) {
+			result[ident.AsIdent()] = true
+		}
+
+		walkTemplate(check.error, func(node parse.Node) {
+			markTemplateField(node, result)
+		})
+	}
+
+	for _, target := range s.redirect.targets {
+		walkTemplate(target.url, func(node parse.Node) {
+			markTemplateField(node, result)
+		})
+	}
+
+	return result
+}
+
+// markTemplateField marks the top level field the node references, e.g. id for {{.id}} or {{$.id}}.
+func markTemplateField(node parse.Node, fields map[string]bool) {
+	switch node := node.(type) {
+	case *parse.FieldNode:
+		fields[node.Ident[0]] = true
+	case *parse.VariableNode:
+		if len(node.Ident) > 1 && node.Ident[0] == "$" {
+			fields[node.Ident[1]] = true
+		}
+	}
+}
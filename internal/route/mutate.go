@@ -0,0 +1,240 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"text/template/parse"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+)
+
+// Mutant is a deliberate alteration of a route check or redirect template.
+// A mutant is killed if at least one test of the route fails against it. A surviving mutant means the tests do not protect the altered part of the route.
+type Mutant struct {
+	RouteIndex int
+	Path       string
+	Title      string
+
+	// Description describes the alteration, e.g. `negate check [0]`.
+	Description string
+
+	// Killed reports whether at least one test of the route failed against the mutant.
+	Killed bool
+
+	// Skipped reports whether the mutant was not run because the tests of the route already fail against the unmutated route.
+	Skipped bool
+}
+
+// comparisonFlips maps each CEL comparison operator to the operator replacing it in mutants.
+var comparisonFlips = map[string]string{
+	"_==_": "!=",
+	"_!=_": "==",
+	"_<_":  ">=",
+	"_<=_": ">",
+	"_>_":  "<=",
+	"_>=_": "<",
+}
+
+// mutation is an altered copy of a route and a description of the alteration.
+type mutation struct {
+	description string
+	route       Route
+}
+
+// RunMutations alters the checks and redirect templates of every route one at a time and runs the tests of the altered route against each alteration.
+// Checks are negated, removed and have each comparison operator flipped. Each literal text of the redirect templates is removed.
+// Alterations which no longer compile are skipped. The mutants of routes whose tests fail against the unmutated routes are not run and are marked as skipped instead.
+// Returns the mutants in route order. Mutants not run due to the context being done are not included.
+func RunMutations(ctx context.Context, routes []Route) []Mutant {
+	baselineMux := newTestMux(NewHandlers(routes))
+
+	result := []Mutant{}
+	for idx, route := range routes {
+		if !route.valid {
+			panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
+		}
+
+		mutations := route.mutations()
+		if len(mutations) == 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			return result
+		}
+		skipped := failsAny(ctx, baselineMux, route.tests)
+
+		for _, mutation := range mutations {
+			if ctx.Err() != nil {
+				return result
+			}
+
+			killed := false
+			if !skipped {
+				mutatedRoutes := slices.Clone(routes)
+				mutatedRoutes[idx] = mutation.route
+				killed = failsAny(ctx, newTestMux(NewHandlers(mutatedRoutes)), route.tests)
+			}
+
+			result = append(result, Mutant{
+				RouteIndex:  idx,
+				Path:        route.paths[0],
+				Title:       route.title,
+				Description: mutation.description,
+				Killed:      killed,
+				Skipped:     skipped,
+			})
+		}
+	}
+
+	return result
+}
+
+// failsAny reports whether at least one of the tests fails against the mux.
+func failsAny(ctx context.Context, mux *http.ServeMux, tests []RouteTest) bool {
+	for _, test := range tests {
+		if testRoute(ctx, mux, test) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mutations returns the compilable alterations of the route checks followed by those of the redirect templates.
+func (s Route) mutations() []mutation {
+	result := []mutation{}
+	for idx, check := range s.checks {
+		if mutated, ok := s.withCheck(idx, "!("+check.source+")"); ok {
+			result = append(result, mutation{description: fmt.Sprintf("negate check [%d]", idx), route: mutated})
+		}
+
+		removed := s
+		removed.checks = slices.Delete(slices.Clone(s.checks), idx, idx+1)
+		result = append(result, mutation{description: fmt.Sprintf("remove check [%d]", idx), route: removed})
+
+		for _, flip := range comparisonMutations(check.source, s.celEnv) {
+			if mutated, ok := s.withCheck(idx, flip.source); ok {
+				result = append(result, mutation{description: fmt.Sprintf("%s in check [%d]", flip.description, idx), route: mutated})
+			}
+		}
+	}
+
+	for tidx, target := range s.redirect.targets {
+		if target.url == nil || target.url.Tree == nil {
+			continue
+		}
+
+		for _, text := range templateTexts(target.url.Tree.Root) {
+			source := target.source[:text.Pos] + target.source[int(text.Pos)+len(text.Text):]
+			parsedURL, err := s.templates.parse(source)
+			if err != nil {
+				continue
+			}
+
+			mutated := s
+			mutated.redirect.targets = slices.Clone(s.redirect.targets)
+			mutated.redirect.targets[tidx].url = parsedURL
+			mutated.redirect.targets[tidx].source = source
+			result = append(result, mutation{
+				description: fmt.Sprintf("remove literal %q from %s", string(text.Text), coverageTarget(target)),
+				route:       mutated,
+			})
+		}
+	}
+
+	return result
+}
+
+// withCheck returns a copy of the route with the expression of the check at the index replaced.
+// Returns false if the expression does not compile.
+func (s Route) withCheck(idx int, source string) (Route, bool) {
+	expr, err := parseRouteCheckExpr(source, s.celEnv)
+	if err != nil {
+		return Route{}, false
+	}
+
+	mutated := s
+	mutated.checks = slices.Clone(s.checks)
+	mutated.checks[idx].expr = expr
+	mutated.checks[idx].source = source
+	return mutated, true
+}
+
+type comparisonMutation struct {
+	description string
+	source      string
+}
+
+// comparisonMutations returns a copy of the expression for each comparison operator in it with the operator flipped.
+func comparisonMutations(source string, env *cel.Env) []comparisonMutation {
+	parsed, issues := env.Parse(source)
+	if issues != nil && issues.Err() != nil {
+		return nil
+	}
+
+	// The offsets of operator calls are those of the operator in the source in code points
+	runes := []rune(source)
+	info := parsed.NativeRep().SourceInfo()
+	operators := []ast.OffsetRange{}
+	flips := map[int32]string{}
+	for _, call := range ast.MatchDescendants(ast.NavigateAST(parsed.NativeRep()), ast.KindMatcher(ast.CallKind)) {
+		flipped, ok := comparisonFlips[call.AsCall().FunctionName()]
+		if !ok {
+			continue
+		}
+
+		offsets, ok := info.GetOffsetRange(call.ID())
+		if !ok || offsets.Start < 0 || int(offsets.Stop) > len(runes) || offsets.Start >= offsets.Stop {
+			continue
+		}
+		operators = append(operators, offsets)
+		flips[offsets.Start] = flipped
+	}
+	slices.SortFunc(operators, func(a ast.OffsetRange, b ast.OffsetRange) int {
+		return int(a.Start - b.Start)
+	})
+
+	result := []comparisonMutation{}
+	for _, offsets := range operators {
+		flipped := flips[offsets.Start]
+		operator := string(runes[offsets.Start:offsets.Stop])
+		result = append(result, comparisonMutation{
+			description: fmt.Sprintf("replace %q with %q at position %d", operator, flipped, offsets.Start),
+			source:      string(runes[:offsets.Start]) + flipped + string(runes[offsets.Stop:]),
+		})
+	}
+
+	return result
+}
+
+// templateTexts returns the non-empty literal texts of the template in order.
+func templateTexts(node parse.Node) []*parse.TextNode {
+	result := []*parse.TextNode{}
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return result
+		}
+		for _, child := range node.Nodes {
+			result = append(result, templateTexts(child)...)
+		}
+	case *parse.IfNode:
+		result = append(result, templateTexts(node.List)...)
+		result = append(result, templateTexts(node.ElseList)...)
+	case *parse.RangeNode:
+		result = append(result, templateTexts(node.List)...)
+		result = append(result, templateTexts(node.ElseList)...)
+	case *parse.WithNode:
+		result = append(result, templateTexts(node.List)...)
+		result = append(result, templateTexts(node.ElseList)...)
+	case *parse.TextNode:
+		if len(node.Text) > 0 {
+			result = append(result, node)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,137 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/ratelimit"
+)
+
+type RouteRateLimit struct {
+	rate    float64
+	burst   int
+	maxKeys int
+	header  string
+	param   string
+	limiter *ratelimit.Limiter
+}
+
+// parseRateLimit parses the rate limit configuration. Returns nil if requests are not limited.
+func parseRateLimit(conf config.RateLimit, params map[string]string) (*RouteRateLimit, error) {
+	if conf.Requests == 0 {
+		return nil, nil
+	}
+	if conf.Requests < 0 {
+		return nil, fmt.Errorf("requests must be positive but was %d", conf.Requests)
+	}
+
+	period, err := time.ParseDuration(conf.Period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse period: %w", err)
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("period must be positive but was %s", conf.Period)
+	}
+
+	burst := conf.Burst
+	if burst == 0 {
+		burst = conf.Requests
+	}
+	if burst < 0 {
+		return nil, fmt.Errorf("burst must be positive but was %d", conf.Burst)
+	}
+
+	if conf.Key.Header != "" && conf.Key.Param != "" {
+		return nil, fmt.Errorf("only one of key header or param may be set")
+	}
+	if _, ok := params[conf.Key.Param]; conf.Key.Param != "" && !ok {
+		return nil, fmt.Errorf("key param %q is not defined in params", conf.Key.Param)
+	}
+
+	result := &RouteRateLimit{
+		rate:    float64(conf.Requests) / period.Seconds(),
+		burst:   burst,
+		maxKeys: conf.MaxKeys,
+		header:  conf.Key.Header,
+		param:   conf.Key.Param,
+	}
+	result.limiter = result.newLimiter()
+
+	return result, nil
+}
+
+func (s *RouteRateLimit) newLimiter() *ratelimit.Limiter {
+	return ratelimit.New(s.rate, s.burst, s.maxKeys)
+}
+
+// key returns the key the request is limited by. Falls back to the client address if the header or param is empty.
+func (s *RouteRateLimit) key(r *http.Request, clientAddr netip.Addr, params map[string]any) string {
+	if s.header != "" {
+		if value := r.Header.Get(s.header); value != "" {
+			return "header:" + value
+		}
+	}
+
+	if s.param != "" {
+		if value, _ := params[s.param].(string); value != "" {
+			return "param:" + value
+		}
+	}
+
+	return "ip:" + clientAddr.String()
+}
+
+// allow consumes a request from the limit. Returns false and the duration to wait if the request is limited.
+func (s *RouteRateLimit) allow(r *http.Request, clientAddr netip.Addr, params map[string]any, now time.Time) (bool, time.Duration) {
+	limiter := s.limiter
+	if scope, ok := r.Context().Value(rateLimitScopeContextKey{}).(*rateLimitScope); ok {
+		if !scope.enabled {
+			return true, 0
+		}
+		limiter = scope.limiter(s)
+	}
+
+	return limiter.Allow(s.key(r, clientAddr, params), now)
+}
+
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
+
+// rateLimitScope isolates rate limit state of route tests from each other and from served requests.
+// A disabled scope does not limit requests at all.
+type rateLimitScope struct {
+	enabled  bool
+	mu       sync.Mutex
+	limiters map[*RouteRateLimit]*ratelimit.Limiter
+}
+
+type rateLimitScopeContextKey struct{}
+
+func withRateLimitScope(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, rateLimitScopeContextKey{}, &rateLimitScope{
+		enabled:  enabled,
+		limiters: map[*RouteRateLimit]*ratelimit.Limiter{},
+	})
+}
+
+func (s *rateLimitScope) limiter(limit *RouteRateLimit) *ratelimit.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limiter, ok := s.limiters[limit]
+	if !ok {
+		limiter = limit.newLimiter()
+		s.limiters[limit] = limiter
+	}
+
+	return limiter
+}
@@ -0,0 +1,203 @@
+package route
+
+import (
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+const (
+	defaultRecordSamples = 10
+
+	// RedactedValue replaces the values of redacted headers in recorded tests.
+	RedactedValue = "REDACTED"
+)
+
+// RecordOptions configure which requests a Recorder samples.
+type RecordOptions struct {
+	// Samples is the maximum number of distinct requests recorded per route. Defaults to 10.
+	Samples int
+
+	// RedactHeaders are the names of headers whose values are replaced with RedactedValue in recorded tests.
+	RedactHeaders []string
+}
+
+// RecordedTests are the tests recorded for a route.
+type RecordedTests struct {
+	RouteIndex int
+	Path       string
+	Title      string
+	Tests      []config.RouteTest
+}
+
+// Recorder samples the requests served by route handlers as route tests expecting the response the request got.
+// Only the path, the query params and headers the param templates of the route reference and the method are recorded.
+// Responses depending on the client identity, address or rate limits (401, 403 and 429) are not recorded.
+// The recorded destination is rendered with the params reading redacted headers replaced with RedactedValue so the test expects what the redacted request redirects to.
+// Requests whose destination fails to render that way are not recorded.
+// The recorded test request urls of group member routes are relative to the group prefix.
+// Requests to routes splitting between weighted targets pin the bucket of the target they were redirected to.
+type Recorder struct {
+	routes  []Route
+	samples int
+	redact  map[string]bool
+
+	mu    sync.Mutex
+	tests map[int][]config.RouteTest
+	seen  map[int]map[string]bool
+}
+
+// NewRecorder creates a recorder sampling requests to the routes.
+func NewRecorder(routes []Route, options RecordOptions) *Recorder {
+	samples := options.Samples
+	if samples <= 0 {
+		samples = defaultRecordSamples
+	}
+
+	redact := make(map[string]bool, len(options.RedactHeaders))
+	for _, name := range options.RedactHeaders {
+		redact[http.CanonicalHeaderKey(name)] = true
+	}
+
+	return &Recorder{
+		routes:  routes,
+		samples: samples,
+		redact:  redact,
+		tests:   map[int][]config.RouteTest{},
+		seen:    map[int]map[string]bool{},
+	}
+}
+
+// Handlers returns the handlers wrapped to record the requests they serve.
+func (s *Recorder) Handlers(handlers []Handler) []Handler {
+	result := make([]Handler, 0, len(handlers))
+	for _, handler := range handlers {
+		next := handler.handler
+		result = append(result, Handler{path: handler.path, handler: func(w http.ResponseWriter, r *http.Request) {
+			trace := &Trace{redactHeaders: s.redact}
+			recorder := &recordResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			next(recorder, r.WithContext(withTrace(r.Context(), trace)))
+
+			if trace.Route != nil {
+				s.record(trace.Route.Index, r, recorder.status, recorder.Header().Get("Location"), trace)
+			}
+		}})
+	}
+
+	return result
+}
+
+// Tests returns the tests recorded so far for every route with at least one recorded test in route order.
+func (s *Recorder) Tests() []RecordedTests {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []RecordedTests{}
+	for idx, route := range s.routes {
+		if len(s.tests[idx]) == 0 {
+			continue
+		}
+		result = append(result, RecordedTests{
+			RouteIndex: idx,
+			Path:       route.paths[0],
+			Title:      route.title,
+			Tests:      slices.Clone(s.tests[idx]),
+		})
+	}
+
+	return result
+}
+
+func (s *Recorder) record(idx int, r *http.Request, status int, location string, trace *Trace) {
+	if idx < 0 || idx >= len(s.routes) {
+		return
+	}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusTooManyRequests {
+		return
+	}
+	if trace.redacted && location != "" {
+		if trace.redactedLocation == "" {
+			return
+		}
+		location = trace.redactedLocation
+	}
+
+	request := s.routes[idx].recordRequest(r, s.redact)
+
+	test := config.RouteTest{
+		Request:  request,
+		Response: config.RouteTestResponse{URL: location},
+	}
+	if status != http.StatusTemporaryRedirect {
+		test.Response.Status = status
+	}
+	if bucket, ok := s.routes[idx].redirect.targetBucket(trace.Target); ok {
+		test.Request.Bucket = &bucket
+	}
+
+	key := RouteTestRequest{method: r.Method, url: test.Request.URL, headers: test.Request.Headers, bucket: test.Request.Bucket}.key()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen[idx] == nil {
+		s.seen[idx] = map[string]bool{}
+	}
+	if s.seen[idx][key] || len(s.tests[idx]) >= s.samples {
+		return
+	}
+	s.seen[idx][key] = true
+	s.tests[idx] = append(s.tests[idx], test)
+}
+
+// recordRequest returns the test request reproducing the request with only the query params and headers the param templates of the route reference.
+// The values of the redacted headers are replaced with RedactedValue.
+func (s Route) recordRequest(r *http.Request, redact map[string]bool) config.RouteTestRequest {
+	queries, headers := s.fuzzInputs()
+
+	result := config.RouteTestRequest{URL: strings.TrimPrefix(r.URL.EscapedPath(), s.prefix)}
+	if r.Method != http.MethodGet {
+		result.Method = r.Method
+	}
+
+	query := neturl.Values{}
+	for _, key := range queries {
+		if values, ok := r.URL.Query()[key]; ok {
+			query[key] = values
+		}
+	}
+	if len(query) > 0 {
+		result.URL += "?" + query.Encode()
+	}
+
+	for _, name := range headers {
+		value := r.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if redact[http.CanonicalHeaderKey(name)] {
+			value = RedactedValue
+		}
+		if result.Headers == nil {
+			result.Headers = map[string]string{}
+		}
+		result.Headers[name] = value
+	}
+
+	return result
+}
+
+// recordResponseWriter captures the status code written by a handler.
+type recordResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *recordResponseWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
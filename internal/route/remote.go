@@ -0,0 +1,104 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxRemoteBodySize is the maximum number of response body bytes read from a remote target for body assertions.
+const maxRemoteBodySize = 1 << 20
+
+// RunRemoteTests sends the test requests of all routes over HTTP to the target instead of the in-process handlers
+// and returns the result of every test in order. The target is the base url the routes are served at, e.g. https://links.example.com.
+// Redirects are not followed so the status and Location header of the target response are asserted like RunTests does.
+// Tests which simulate state the target cannot be told about (environment overrides, a pinned time, weighted redirect bucket
+// or client address) or which would exhaust its rate limits are skipped.
+// Tests not run due to the context being done are not included.
+func RunRemoteTests(ctx context.Context, routes []Route, target *url.URL, client *http.Client) []TestResult {
+	noRedirectClient := *client
+	noRedirectClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	results := []TestResult{}
+	for idx, route := range routes {
+		if !route.valid {
+			panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
+		}
+
+		for _, test := range route.tests {
+			if ctx.Err() != nil {
+				return results
+			}
+
+			result := TestResult{
+				RouteIndex: idx,
+				Path:       route.paths[0],
+				Title:      route.title,
+				TestIndex:  test.index,
+				Row:        test.row,
+				Method:     test.request.method,
+				URL:        test.request.url,
+				Skipped:    test.remoteSkipReason(),
+			}
+			if result.Skipped == "" {
+				start := time.Now()
+				result.Failure = testRemoteRoute(ctx, &noRedirectClient, target, test)
+				result.Duration = time.Since(start)
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// remoteSkipReason returns why the test cannot be run against a remote target. Returns an empty string if it can.
+func (s RouteTest) remoteSkipReason() string {
+	switch {
+	case len(s.request.environment.values) > 0:
+		return "test overrides the environment"
+	case !s.request.time.IsZero():
+		return "test pins the request time"
+	case s.request.bucket != nil:
+		return "test pins the weighted redirect bucket"
+	case s.request.remoteAddr != "":
+		return "test simulates the client address"
+	case s.response.rateLimitedAfter > 0:
+		return "test exhausts the rate limit"
+	default:
+		return ""
+	}
+}
+
+func testRemoteRoute(ctx context.Context, client *http.Client, target *url.URL, test RouteTest) *TestFailure {
+	requestURL := strings.TrimSuffix(target.String(), "/") + test.request.url
+	req, err := http.NewRequestWithContext(ctx, test.request.method, requestURL, nil)
+	if err != nil {
+		return &TestFailure{Message: fmt.Sprintf("failed to create request: %v", err)}
+	}
+	for k, v := range test.request.headers {
+		req.Header.Add(k, v)
+	}
+	for name, value := range test.request.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &TestFailure{Message: fmt.Sprintf("request failed: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteBodySize))
+	if err != nil {
+		return &TestFailure{Message: fmt.Sprintf("failed to read response body: %v", err)}
+	}
+
+	return checkTestResponse(resp.StatusCode, resp.Header, string(body), test.response)
+}
@@ -0,0 +1,133 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TestFailure describes why a route test failed with the expected and actual values if applicable.
+type TestFailure struct {
+	Message  string
+	Expected string
+	Actual   string
+}
+
+func (s *TestFailure) Error() string {
+	return s.Message
+}
+
+// TestRow identifies the row of a parameterized test a test was expanded from.
+type TestRow struct {
+	Index  int
+	Values map[string]string
+}
+
+// TestResult is the result of running a single route test.
+type TestResult struct {
+	// RouteIndex is the index of the route the test is defined in.
+	RouteIndex int
+
+	// Path is the path of the route the test is defined in.
+	Path string
+
+	// Title is the documentation title of the route the test is defined in, if any.
+	Title string
+
+	// TestIndex is the index of the test in the tests of the route.
+	TestIndex int
+
+	// Row is the row of the parameterized test the test was expanded from. Nil if the test is not parameterized.
+	Row *TestRow
+
+	// Method and URL are the request method and url the test sent.
+	Method string
+	URL    string
+
+	// Duration is the time it took to run the test.
+	Duration time.Duration
+
+	// Failure is the reason the test failed. Nil if the test passed or was skipped.
+	Failure *TestFailure
+
+	// Skipped is the reason the test was not run, if any.
+	Skipped string
+}
+
+// Name returns a human-readable name identifying the test.
+func (s TestResult) Name() string {
+	return fmt.Sprintf("%s for route %q at index [%d]", testName(s.TestIndex, s.Row), s.Path, s.RouteIndex)
+}
+
+// testName returns the name of the test at the index of the tests of a route, e.g. test [0] row [1].
+func testName(index int, row *TestRow) string {
+	if row == nil {
+		return fmt.Sprintf("test [%d]", index)
+	}
+
+	return fmt.Sprintf("test [%d] row [%d]", index, row.Index)
+}
+
+func (s RouteTest) name() string {
+	return testName(s.index, s.row)
+}
+
+// Passed reports whether the test was run and passed.
+func (s TestResult) Passed() bool {
+	return s.Failure == nil && s.Skipped == ""
+}
+
+// RunTests runs the tests of all routes against the handlers and returns the result of every test in order.
+// Tests not run due to the context being done are not included.
+func RunTests(ctx context.Context, routes []Route, handlers []Handler) []TestResult {
+	results, _ := RunTestsWithCoverage(ctx, routes, handlers)
+	return results
+}
+
+// RunTestsWithCoverage runs the tests like RunTests and additionally returns the coverage of every route by the tests.
+func RunTestsWithCoverage(ctx context.Context, routes []Route, handlers []Handler) ([]TestResult, []RouteCoverage) {
+	recorder := &coverageRecorder{hits: map[int]map[string]bool{}}
+	ctx = withCoverage(ctx, recorder)
+
+	mux := newTestMux(handlers)
+
+	results := []TestResult{}
+	for idx, route := range routes {
+		if !route.valid {
+			panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
+		}
+
+		for _, test := range route.tests {
+			if ctx.Err() != nil {
+				return results, recorder.coverage(routes)
+			}
+
+			start := time.Now()
+			failure := testRoute(ctx, mux, test)
+			results = append(results, TestResult{
+				RouteIndex: idx,
+				Path:       route.paths[0],
+				Title:      route.title,
+				TestIndex:  test.index,
+				Row:        test.row,
+				Method:     test.request.method,
+				URL:        test.request.url,
+				Duration:   time.Since(start),
+				Failure:    failure,
+			})
+		}
+	}
+
+	return results, recorder.coverage(routes)
+}
+
+// newTestMux returns a mux serving the handlers the route tests are sent to.
+func newTestMux(handlers []Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, handler := range handlers {
+		mux.HandleFunc(handler.Route(), handler.Handler())
+	}
+
+	return mux
+}
@@ -0,0 +1,41 @@
+package route
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// RequestLog collects route specific attributes of a request, such as the chosen redirect target, for access logging.
+type RequestLog struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type requestLogContextKey struct{}
+
+// WithRequestLog attaches a new request log to the context. Route handlers record their attributes into it.
+func WithRequestLog(ctx context.Context) (context.Context, *RequestLog) {
+	log := &RequestLog{}
+	return context.WithValue(ctx, requestLogContextKey{}, log), log
+}
+
+// Attrs returns the attributes recorded for the request.
+func (s *RequestLog) Attrs() []slog.Attr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]slog.Attr(nil), s.attrs...)
+}
+
+// logAttrs records attributes into the request log of the context if one is attached.
+func logAttrs(ctx context.Context, attrs ...slog.Attr) {
+	log, ok := ctx.Value(requestLogContextKey{}).(*RequestLog)
+	if !ok {
+		return
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.attrs = append(log.attrs, attrs...)
+}
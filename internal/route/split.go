@@ -0,0 +1,195 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+const stickyCookieMaxAge = 365 * 24 * time.Hour
+
+type RouteRedirectTarget struct {
+	name   string
+	url    *template.Template
+	source string
+	weight int
+}
+
+type RouteRedirectSticky struct {
+	cookie string
+	header string
+	param  string
+}
+
+type RouteRedirect struct {
+	targets     []RouteRedirectTarget
+	totalWeight int
+	sticky      RouteRedirectSticky
+}
+
+func parseRouteRedirect(redirect config.RouteRedirect, params map[string]string, templates *RouteTemplates) (RouteRedirect, error) {
+	if len(redirect.Targets) == 0 {
+		parsedURL, err := parseTemplate(redirect.URL, templates)
+		if err != nil {
+			return RouteRedirect{}, err
+		}
+
+		return RouteRedirect{
+			targets:     []RouteRedirectTarget{{url: parsedURL, source: redirect.URL, weight: 1}},
+			totalWeight: 1,
+		}, nil
+	}
+
+	if redirect.URL != "" {
+		return RouteRedirect{}, fmt.Errorf("redirect url and targets are mutually exclusive")
+	}
+
+	result := RouteRedirect{}
+	names := make(map[string]bool, len(redirect.Targets))
+	for idx, target := range redirect.Targets {
+		if target.Name == "" {
+			return RouteRedirect{}, fmt.Errorf("redirect target [%d] is missing a name", idx)
+		}
+		if names[target.Name] {
+			return RouteRedirect{}, fmt.Errorf("redirect target name %q is defined more than once", target.Name)
+		}
+		names[target.Name] = true
+
+		if target.Weight < 0 {
+			return RouteRedirect{}, fmt.Errorf("redirect target %q weight must not be negative", target.Name)
+		}
+
+		parsedURL, err := parseTemplate(target.URL, templates)
+		if err != nil {
+			return RouteRedirect{}, fmt.Errorf("failed to parse redirect target %q: %w", target.Name, err)
+		}
+
+		result.targets = append(result.targets, RouteRedirectTarget{
+			name:   target.Name,
+			url:    parsedURL,
+			source: target.URL,
+			weight: target.Weight,
+		})
+		result.totalWeight += target.Weight
+	}
+
+	if result.totalWeight == 0 {
+		return RouteRedirect{}, fmt.Errorf("redirect targets must have a positive total weight")
+	}
+
+	sticky := redirect.Sticky
+	configured := 0
+	for _, value := range []string{sticky.Cookie, sticky.Header, sticky.Param} {
+		if value != "" {
+			configured++
+		}
+	}
+	if configured > 1 {
+		return RouteRedirect{}, fmt.Errorf("only one of sticky cookie, header or param may be set")
+	}
+	if _, ok := params[sticky.Param]; sticky.Param != "" && !ok {
+		return RouteRedirect{}, fmt.Errorf("sticky param %q is not defined in params", sticky.Param)
+	}
+	result.sticky = RouteRedirectSticky{
+		cookie: sticky.Cookie,
+		header: sticky.Header,
+		param:  sticky.Param,
+	}
+
+	return result, nil
+}
+
+// choose picks the redirect target for the request.
+// Sticky cookie assignments are persisted by setting the cookie on the response.
+func (s RouteRedirect) choose(w http.ResponseWriter, r *http.Request, params map[string]any) RouteRedirectTarget {
+	if len(s.targets) == 1 {
+		return s.targets[0]
+	}
+
+	if s.sticky.cookie != "" {
+		if cookie, err := r.Cookie(s.sticky.cookie); err == nil {
+			for _, target := range s.targets {
+				if target.name == cookie.Value && target.weight > 0 {
+					return target
+				}
+			}
+		}
+	}
+
+	target := s.targetForBucket(s.bucket(r, params))
+
+	if s.sticky.cookie != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     s.sticky.cookie,
+			Value:    target.name,
+			Path:     "/",
+			MaxAge:   int(stickyCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return target
+}
+
+func (s RouteRedirect) bucket(r *http.Request, params map[string]any) int {
+	if bucket, ok := r.Context().Value(bucketContextKey{}).(int); ok {
+		return bucket
+	}
+
+	key := ""
+	if s.sticky.header != "" {
+		key = r.Header.Get(s.sticky.header)
+	} else if s.sticky.param != "" {
+		key, _ = params[s.sticky.param].(string)
+	}
+
+	if key == "" {
+		return rand.IntN(s.totalWeight)
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(s.totalWeight))
+}
+
+// targetBucket returns the first bucket assigned to the named target. Returns false if requests are not split between targets.
+func (s RouteRedirect) targetBucket(name string) (int, bool) {
+	if len(s.targets) < 2 {
+		return 0, false
+	}
+
+	bucket := 0
+	for _, target := range s.targets {
+		if target.name == name && target.weight > 0 {
+			return bucket, true
+		}
+		bucket += target.weight
+	}
+
+	return 0, false
+}
+
+func (s RouteRedirect) targetForBucket(bucket int) RouteRedirectTarget {
+	for _, target := range s.targets {
+		if bucket < target.weight {
+			return target
+		}
+		bucket -= target.weight
+	}
+
+	return s.targets[len(s.targets)-1]
+}
+
+type bucketContextKey struct{}
+
+// withBucket pins the weighted redirect bucket requests are assigned to. Used by route tests to deterministically pick a target.
+func withBucket(ctx context.Context, bucket int) context.Context {
+	return context.WithValue(ctx, bucketContextKey{}, bucket)
+}
@@ -0,0 +1,211 @@
+package route
+
+import (
+	"fmt"
+	"slices"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+// RouteTemplates are the named templates every route template is parsed alongside.
+// The names referenced by route templates are tracked to report unused named templates.
+type RouteTemplates struct {
+	base *template.Template
+	used map[string]bool
+}
+
+func parseRouteTemplates(templates map[string]string) (*RouteTemplates, error) {
+	result := &RouteTemplates{
+		base: template.New(""),
+		used: map[string]bool{},
+	}
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("template name must not be empty")
+		}
+		if _, err := result.base.New(name).Parse(templates[name]); err != nil {
+			return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+		}
+	}
+
+	for _, name := range names {
+		if err := result.checkReferences(result.base.Lookup(name)); err != nil {
+			return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// parse parses the template alongside the named templates.
+func (s *RouteTemplates) parse(value string) (*template.Template, error) {
+	base, err := s.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTemplate, err := base.New("").Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkReferences(parsedTemplate); err != nil {
+		return nil, err
+	}
+
+	return parsedTemplate, nil
+}
+
+// checkReferences fails if the template references an undefined template and marks the referenced templates as used.
+func (s *RouteTemplates) checkReferences(tmpl *template.Template) error {
+	if tmpl == nil || tmpl.Tree == nil {
+		return nil
+	}
+
+	for _, name := range templateReferences(tmpl.Tree.Root) {
+		if tmpl.Lookup(name) == nil {
+			return fmt.Errorf("template %q is not defined", name)
+		}
+		if tmpl.Name() != name {
+			s.used[name] = true
+		}
+	}
+
+	return nil
+}
+
+// unused returns the sorted names of the named templates not referenced by any template.
+func (s *RouteTemplates) unused() []string {
+	result := []string{}
+	for _, tmpl := range s.base.Templates() {
+		if name := tmpl.Name(); name != "" && !s.used[name] {
+			result = append(result, name)
+		}
+	}
+	slices.Sort(result)
+
+	return result
+}
+
+// unusedTemplates returns the sorted names of the named templates of the configuration not referenced by the templates
+// of any route or group member route. Templates failing to parse are ignored as NewRoutes reports them.
+func unusedTemplates(conf config.Config) []string {
+	templates, err := parseRouteTemplates(conf.Templates)
+	if err != nil {
+		return []string{}
+	}
+
+	routes, err := expandRoutes(conf)
+	if err != nil {
+		return []string{}
+	}
+
+	for _, route := range routes {
+		values := []string{route.Redirect.URL}
+		for _, value := range route.Params {
+			values = append(values, value)
+		}
+		for _, check := range route.Checks {
+			values = append(values, check.Error)
+		}
+		for _, target := range route.Redirect.Targets {
+			values = append(values, target.URL)
+		}
+
+		for _, value := range values {
+			_, _ = templates.parse(value)
+		}
+	}
+
+	return templates.unused()
+}
+
+func templateReferences(node parse.Node) []string {
+	result := []string{}
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return result
+		}
+		for _, child := range node.Nodes {
+			result = append(result, templateReferences(child)...)
+		}
+	case *parse.IfNode:
+		result = append(result, templateReferences(node.List)...)
+		result = append(result, templateReferences(node.ElseList)...)
+	case *parse.RangeNode:
+		result = append(result, templateReferences(node.List)...)
+		result = append(result, templateReferences(node.ElseList)...)
+	case *parse.WithNode:
+		result = append(result, templateReferences(node.List)...)
+		result = append(result, templateReferences(node.ElseList)...)
+	case *parse.TemplateNode:
+		result = append(result, node.Name)
+	}
+
+	return result
+}
+
+// walkTemplate calls visit for every node of the template and of the named templates it references.
+func walkTemplate(tmpl *template.Template, visit func(node parse.Node)) {
+	visited := map[string]bool{}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch node := node.(type) {
+		case *parse.ListNode:
+			if node == nil {
+				return
+			}
+			for _, child := range node.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(node.Pipe)
+		case *parse.PipeNode:
+			if node == nil {
+				return
+			}
+			for _, cmd := range node.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			visit(node)
+			for _, arg := range node.Args {
+				walk(arg)
+			}
+		case *parse.IfNode:
+			walk(node.Pipe)
+			walk(node.List)
+			walk(node.ElseList)
+		case *parse.RangeNode:
+			walk(node.Pipe)
+			walk(node.List)
+			walk(node.ElseList)
+		case *parse.WithNode:
+			walk(node.Pipe)
+			walk(node.List)
+			walk(node.ElseList)
+		case *parse.TemplateNode:
+			walk(node.Pipe)
+			if referenced := tmpl.Lookup(node.Name); referenced != nil && referenced.Tree != nil && !visited[node.Name] {
+				visited[node.Name] = true
+				walk(referenced.Tree.Root)
+			}
+		default:
+			visit(node)
+		}
+	}
+
+	if tmpl != nil && tmpl.Tree != nil {
+		walk(tmpl.Tree.Root)
+	}
+}
@@ -0,0 +1,209 @@
+package route
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Trace describes how the route handlers evaluated a request.
+type Trace struct {
+	// Method and URL are the request method and url.
+	Method string
+	URL    string
+
+	// Route is the route the request matched. Nil if no route matched.
+	Route *TraceRoute
+
+	// Params are the params extracted from the request sorted by name.
+	Params []TraceParam
+
+	// Checks are the checks evaluated in order. Evaluation stops at the first failed check.
+	Checks []TraceCheck
+
+	// Target is the name of the chosen weighted redirect target, if any.
+	Target string
+
+	// TemplateError is the error executing a param, check error or redirect template, if any.
+	TemplateError string
+
+	// Status, Location and Body describe the response.
+	Status   int
+	Location string
+	Body     string
+
+	// redactHeaders are the canonical names of the headers whose values must not appear in redactedLocation. Set by the Recorder.
+	redactHeaders map[string]bool
+
+	// redactedLocation is the redirect url rendered with the params reading redacted headers replaced with RedactedValue.
+	// Only set if a param read a redacted header, redacted reports whether it was. Empty if the url failed to render.
+	redactedLocation string
+	redacted         bool
+}
+
+// TraceRoute identifies the route a request matched.
+type TraceRoute struct {
+	Index int
+	Path  string
+	Title string
+
+	// Pattern is the path or alias of the route the request matched.
+	Pattern string
+}
+
+// TraceParam is a param extracted from the request.
+type TraceParam struct {
+	Name string
+
+	// Sources are the inputs the value was read from in order of the first read, e.g. path "id", query "q", header "X-Lang" or env "HOST".
+	// Empty if the value does not depend on the request.
+	Sources []string
+
+	// Template is the template the param is extracted with.
+	Template string
+	Value    string
+}
+
+// TraceCheck is the result of evaluating a check.
+type TraceCheck struct {
+	Index  int
+	Expr   string
+	Passed bool
+}
+
+// ResolveRequest is a request to resolve against the routes.
+type ResolveRequest struct {
+	// URL is the request path with an optional query.
+	URL string
+
+	Headers map[string]string
+
+	// Environment takes precedence over the environment snapshot of the routes for allowlisted variables.
+	Environment Environment
+}
+
+type traceContextKey struct{}
+
+func withTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+func traceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceContextKey{}).(*Trace)
+	return trace
+}
+
+// Resolve sends the request through the handlers of the routes and returns a trace of how it was evaluated.
+func Resolve(ctx context.Context, routes []Route, request ResolveRequest) (Trace, error) {
+	if !strings.HasPrefix(request.URL, "/") {
+		return Trace{}, fmt.Errorf("url %q must be an absolute path (start with slash)", request.URL)
+	}
+
+	trace := &Trace{Method: http.MethodGet, URL: request.URL}
+	ctx = withTrace(ctx, trace)
+	ctx = withEnvironmentOverrides(ctx, request.Environment)
+
+	req, err := http.NewRequestWithContext(ctx, trace.Method, request.URL, nil)
+	if err != nil {
+		return Trace{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.RemoteAddr = "192.0.2.1:1234"
+	for key, value := range request.Headers {
+		req.Header.Add(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	newTestMux(NewHandlers(routes)).ServeHTTP(w, req)
+
+	slices.SortFunc(trace.Params, func(a TraceParam, b TraceParam) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	trace.Status = w.Code
+	trace.Location = w.Header().Get("Location")
+	trace.Body = strings.TrimSpace(w.Body.String())
+
+	return *trace, nil
+}
+
+func (s *Trace) match(route Route, path string) {
+	if s == nil {
+		return
+	}
+
+	s.Route = &TraceRoute{
+		Index:   route.index,
+		Path:    route.paths[0],
+		Title:   route.title,
+		Pattern: path,
+	}
+}
+
+func (s *Trace) param(key string, expr *template.Template, value string, sources []string) {
+	if s == nil {
+		return
+	}
+
+	tmpl := ""
+	if expr.Tree != nil {
+		tmpl = expr.Tree.Root.String()
+	}
+	s.Params = append(s.Params, TraceParam{Name: key, Sources: slices.Clone(sources), Template: tmpl, Value: value})
+}
+
+func (s *Trace) check(idx int, check RouteCheck, passed bool) {
+	if s == nil {
+		return
+	}
+
+	s.Checks = append(s.Checks, TraceCheck{Index: idx, Expr: check.source, Passed: passed})
+}
+
+func (s *Trace) templateError(err error) {
+	if s == nil {
+		return
+	}
+
+	s.TemplateError = err.Error()
+}
+
+func (s *Trace) target(target RouteRedirectTarget) {
+	if s == nil {
+		return
+	}
+
+	s.Target = target.name
+}
+
+// readsRedacted reports whether any of the param sources is a header redacted from the trace.
+func (s *Trace) readsRedacted(sources []string) bool {
+	if s == nil || len(s.redactHeaders) == 0 {
+		return false
+	}
+
+	for _, source := range sources {
+		quoted, ok := strings.CutPrefix(source, "header ")
+		if !ok {
+			continue
+		}
+		if name, err := strconv.Unquote(quoted); err == nil && s.redactHeaders[http.CanonicalHeaderKey(name)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Trace) redactedRedirect(url string) {
+	if s == nil {
+		return
+	}
+
+	s.redactedLocation = url
+	s.redacted = true
+}
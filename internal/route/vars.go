@@ -0,0 +1,34 @@
+package route
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// varsParam is the reserved param the configuration vars are exposed as to checks and templates.
+const varsParam = "vars"
+
+// parseRouteVars returns the configuration vars exposed to the route. Returns nil if there are none.
+func parseRouteVars(vars map[string]string, params map[string]string) (map[string]string, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := params[varsParam]; ok {
+		return nil, fmt.Errorf("param %q is reserved for the configuration vars", varsParam)
+	}
+
+	return vars, nil
+}
+
+// varsCelOptions returns the CEL environment options exposing the configuration vars as vars.x.
+func varsCelOptions(vars map[string]string) []cel.EnvOption {
+	if vars == nil {
+		return nil
+	}
+
+	return []cel.EnvOption{
+		cel.Variable(varsParam, cel.MapType(cel.StringType, cel.StringType)),
+	}
+}
@@ -0,0 +1,36 @@
+package route
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+// Warnings returns non-fatal issues found in the routes validated from the configuration at the given time,
+// such as deprecated paths past their sunset date, routes past their active window or named templates not used by any route.
+func Warnings(conf config.Config, routes []Route, now time.Time) []string {
+	warnings := []string{}
+	for idx, route := range routes {
+		if !route.valid {
+			panic(fmt.Errorf("route at index %d has not been validated - create the routes using NewRoutes", idx))
+		}
+
+		if route.window.expired(now) {
+			warnings = append(warnings, fmt.Sprintf("route at index [%d] is no longer active since %s", idx, route.window.until.Format(time.RFC3339)))
+		}
+
+		for _, path := range route.paths {
+			deprecation, ok := route.deprecations[path]
+			if ok && deprecation.sunsetPassed(now) {
+				warnings = append(warnings, fmt.Sprintf("deprecated path %q of route at index [%d] passed its sunset date %s", path, idx, deprecation.sunset.Format(time.RFC3339)))
+			}
+		}
+	}
+
+	for _, name := range unusedTemplates(conf) {
+		warnings = append(warnings, fmt.Sprintf("template %q is not used by any route", name))
+	}
+
+	return warnings
+}
@@ -0,0 +1,103 @@
+package route_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+)
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+
+	conf := config.Config{Templates: map[string]string{
+		"base":   "https://{{.host}}",
+		"unused": "https://unused.example.com",
+		"nested": `{{template "base" .}}/nested`,
+		"orphan": "https://orphan.example.com",
+	}, Routes: []config.Route{
+		buildTestRoute(func(route *config.Route) {
+			route.Redirect.URL = `{{template "nested" .}}/{{.path}}`
+			route.ActiveUntil = "2024-01-01T00:00:00Z"
+			route.Deprecations = []config.RouteDeprecation{
+				{Path: "/example/{rest}", Sunset: "2030-01-01T00:00:00Z"},
+				{Path: "/example2/{rest}", Sunset: "2020-01-01T00:00:00Z"},
+			}
+		}),
+	}}
+	routes, err := route.NewRoutes(conf, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create test routes: %v", err)
+	}
+
+	expected := []string{
+		"route at index [0] is no longer active since 2024-01-01T00:00:00Z",
+		"deprecated path \"/example2/{rest}\" of route at index [0] passed its sunset date 2020-01-01T00:00:00Z",
+		"template \"orphan\" is not used by any route",
+		"template \"unused\" is not used by any route",
+	}
+	warnings := route.Warnings(conf, routes, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !slices.Equal(warnings, expected) {
+		t.Fatalf("expected warnings %q but got %q", expected, warnings)
+	}
+}
+
+func TestWarnings_Templates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		config      config.Config
+		expected    []string
+	}{
+		{
+			description: "reports templates without any routes",
+			config:      config.Config{Templates: map[string]string{"base": "https://example.com"}},
+			expected:    []string{"template \"base\" is not used by any route"},
+		},
+		{
+			description: "counts templates used by group member routes and shared group params",
+			config: config.Config{
+				Templates: map[string]string{
+					"base":   "https://example.com",
+					"shared": "{{.path}}",
+					"unused": "https://unused.example.com",
+				},
+				Routes: []config.Route{
+					buildTestRoute(),
+				},
+				Groups: []config.RouteGroup{
+					{
+						Prefix: "/group",
+						Params: map[string]string{"shared": `{{template "shared" .}}`},
+						Routes: []config.Route{
+							{
+								Path:     "/member",
+								Redirect: config.RouteRedirect{URL: `{{template "base" .}}/{{.shared}}`},
+							},
+						},
+					},
+				},
+			},
+			expected: []string{"template \"unused\" is not used by any route"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			routes, err := route.NewRoutes(test.config, route.Environment{})
+			if err != nil {
+				t.Fatalf("failed to create test routes: %v", err)
+			}
+
+			warnings := route.Warnings(test.config, routes, time.Now())
+			if !slices.Equal(warnings, test.expected) {
+				t.Fatalf("expected warnings %q but got %q", test.expected, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+)
+
+type RouteWindow struct {
+	from     time.Time
+	until    time.Time
+	status   int
+	fallback string
+}
+
+func parseRouteWindow(from string, until string, inactive config.RouteInactive) (RouteWindow, error) {
+	result := RouteWindow{
+		status:   http.StatusNotFound,
+		fallback: inactive.URL,
+	}
+
+	if from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return RouteWindow{}, fmt.Errorf("failed to parse active from: %w", err)
+		}
+		result.from = parsed
+	}
+
+	if until != "" {
+		parsed, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return RouteWindow{}, fmt.Errorf("failed to parse active until: %w", err)
+		}
+		result.until = parsed
+	}
+
+	if !result.from.IsZero() && !result.until.IsZero() && !result.from.Before(result.until) {
+		return RouteWindow{}, fmt.Errorf("active from %s must be before active until %s", from, until)
+	}
+
+	switch inactive.Status {
+	case 0:
+	case http.StatusNotFound, http.StatusGone:
+		result.status = inactive.Status
+	default:
+		return RouteWindow{}, fmt.Errorf("inactive status must be %d or %d but was %d", http.StatusNotFound, http.StatusGone, inactive.Status)
+	}
+
+	return result, nil
+}
+
+// expired reports whether the window has ended at the given time.
+func (s RouteWindow) expired(now time.Time) bool {
+	return !s.until.IsZero() && !now.Before(s.until)
+}
+
+// active reports whether the window is active at the given time.
+func (s RouteWindow) active(now time.Time) bool {
+	if !s.from.IsZero() && now.Before(s.from) {
+		return false
+	}
+
+	return !s.expired(now)
+}
+
+func (s RouteWindow) writeInactive(w http.ResponseWriter, r *http.Request, now time.Time) {
+	if s.fallback != "" {
+		http.Redirect(w, r, s.fallback, http.StatusTemporaryRedirect)
+		return
+	}
+
+	if s.expired(now) {
+		http.Error(w, "route is no longer active", s.status)
+		return
+	}
+
+	http.Error(w, "route is not active yet", s.status)
+}
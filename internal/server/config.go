@@ -2,6 +2,8 @@ package server
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"embed"
 	"errors"
 	"fmt"
@@ -10,7 +12,9 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/slightly-inconvenient/murl/internal/auth"
 	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
@@ -21,13 +25,17 @@ import (
 var templates embed.FS
 
 type TLSConfig struct {
-	cert string
-	key  string
+	cert       string
+	key        string
+	clientCAs  *x509.CertPool
+	clientAuth tls.ClientAuthType
 }
 
 type DocumentationConfig struct {
-	path    string
-	content []byte
+	path          string
+	content       []byte
+	publicContent []byte
+	authenticator *auth.Authenticator
 }
 
 type Config struct {
@@ -37,8 +45,10 @@ type Config struct {
 	valid         bool
 }
 
-// NewConfig parses the input server configuration and returns a validated server configuration.
-func NewConfig(conf config.Server, routes []config.Route) (Config, error) {
+// NewConfig parses the server configuration of the input configuration and returns a validated server configuration.
+// The documentation authenticates with the authentication providers of the routes created from the same input configuration.
+func NewConfig(input config.Config, routes []route.Route) (Config, error) {
+	conf := input.Server
 	if conf.Address == "" {
 		return Config{}, fmt.Errorf("server address is required")
 	}
@@ -63,27 +73,143 @@ func NewConfig(conf config.Server, routes []config.Route) (Config, error) {
 		}
 	}
 
-	documentation, err := renderDocumentation(conf.Documentation, routes)
+	clientCAs, clientAuth, err := parseClientAuth(conf.TLS)
 	if err != nil {
-		return Config{}, fmt.Errorf("failed to render documentation: %w", err)
+		return Config{}, err
+	}
+
+	documentation, err := parseDocumentation(conf.Documentation, input, routes)
+	if err != nil {
+		return Config{}, err
 	}
 
 	return Config{
 		address: conf.Address,
 		tls: TLSConfig{
-			cert: conf.TLS.Cert,
-			key:  conf.TLS.Key,
+			cert:       conf.TLS.Cert,
+			key:        conf.TLS.Key,
+			clientCAs:  clientCAs,
+			clientAuth: clientAuth,
 		},
 		documentation: documentation,
 		valid:         true,
 	}, nil
 }
 
+func parseClientAuth(conf config.ServerTLSConfig) (*x509.CertPool, tls.ClientAuthType, error) {
+	if conf.ClientCA == "" {
+		if conf.ClientAuth != "" {
+			return nil, tls.NoClientCert, fmt.Errorf("server TLS client CA is required when client auth is provided")
+		}
+		return nil, tls.NoClientCert, nil
+	}
+
+	if conf.Cert == "" {
+		return nil, tls.NoClientCert, fmt.Errorf("server TLS cert and key are required when client CA is provided")
+	}
+
+	clientAuth := tls.RequireAndVerifyClientCert
+	switch conf.ClientAuth {
+	case "", "require":
+	case "optional":
+		clientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, tls.NoClientCert, fmt.Errorf("server TLS client auth must be either \"require\" or \"optional\" but was %q", conf.ClientAuth)
+	}
+
+	content, err := os.ReadFile(conf.ClientCA)
+	if err != nil {
+		return nil, tls.NoClientCert, fmt.Errorf("failed to read server TLS client CA file at path %q: %w", conf.ClientCA, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, tls.NoClientCert, fmt.Errorf("server TLS client CA file at path %q contains no PEM encoded certificates", conf.ClientCA)
+	}
+
+	return pool, clientAuth, nil
+}
+
+func parseDocumentation(conf config.ServerDocumentationConfig, input config.Config, routes []route.Route) (DocumentationConfig, error) {
+	documentedRoutes, err := documentationRoutes(input)
+	if err != nil {
+		return DocumentationConfig{}, err
+	}
+
+	documentation, err := renderDocumentation(conf, documentedRoutes)
+	if err != nil {
+		return DocumentationConfig{}, fmt.Errorf("failed to render documentation: %w", err)
+	}
+
+	if !conf.HideProtected {
+		return documentation, nil
+	}
+
+	if conf.Authentication == "" {
+		return DocumentationConfig{}, fmt.Errorf("documentation authentication is required when hiding protected routes")
+	}
+
+	authenticator, ok := route.Authenticator(routes, conf.Authentication)
+	if !ok {
+		return DocumentationConfig{}, fmt.Errorf("documentation authentication provider %q is not defined", conf.Authentication)
+	}
+
+	publicRoutes := make([]documentationRoute, 0, len(documentedRoutes))
+	var group *config.RouteGroup
+	for _, route := range documentedRoutes {
+		if route.Authentication == "" {
+			// The group section starts with the first public member route
+			route.GroupStart = route.Group != nil && route.Group != group
+			group = route.Group
+			publicRoutes = append(publicRoutes, route)
+		}
+	}
+
+	publicDocumentation, err := renderDocumentation(conf, publicRoutes)
+	if err != nil {
+		return DocumentationConfig{}, fmt.Errorf("failed to render documentation: %w", err)
+	}
+
+	documentation.publicContent = publicDocumentation.content
+	documentation.authenticator = authenticator
+
+	return documentation, nil
+}
+
+// documentationRoute is a route as listed in the documentation.
+// Member routes of groups reference their group, and the first member route of each group starts its section.
+type documentationRoute struct {
+	config.Route
+	Group      *config.RouteGroup
+	GroupStart bool
+}
+
+// documentationRoutes returns the routes followed by the expanded member routes of each group.
+func documentationRoutes(input config.Config) ([]documentationRoute, error) {
+	result := make([]documentationRoute, 0, len(input.Routes))
+	for _, r := range input.Routes {
+		result = append(result, documentationRoute{Route: r})
+	}
+
+	for idx := range input.Groups {
+		group := &input.Groups[idx]
+		routes, err := route.ExpandGroup(*group)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand group at index [%d]: %w", idx, err)
+		}
+		for ridx, r := range routes {
+			result = append(result, documentationRoute{Route: r, Group: group, GroupStart: ridx == 0})
+		}
+	}
+
+	return result, nil
+}
+
 type docsPageHtmlInput struct {
 	Content string
 }
 
-func renderDocumentation(config config.ServerDocumentationConfig, routes []config.Route) (DocumentationConfig, error) {
+func renderDocumentation(config config.ServerDocumentationConfig, routes []documentationRoute) (DocumentationConfig, error) {
 	tmpl := template.New("")
 	for name, path := range map[string]string{
 		"page":    "templates/page.html.tmpl",
@@ -2,6 +2,7 @@ package server_test
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/slightly-inconvenient/murl/internal/config"
@@ -29,7 +30,7 @@ func TestConfig_Succes(t *testing.T) {
 	t.Run("http server", func(t *testing.T) {
 		t.Parallel()
 		input := buildTestServerConfig()
-		_, err := server.NewConfig(input, []config.Route{})
+		_, err := server.NewConfig(config.Config{Server: input}, nil)
 		if err != nil {
 			t.Fatalf("expected create server config to succeed but got error: %s", err)
 		}
@@ -44,7 +45,7 @@ func TestConfig_Succes(t *testing.T) {
 				Key:  keyFile,
 			}
 		})
-		_, err := server.NewConfig(input, []config.Route{})
+		_, err := server.NewConfig(config.Config{Server: input}, nil)
 		if err != nil {
 			t.Fatalf("expected create server config to succeed but got error: %s", err)
 		}
@@ -53,7 +54,7 @@ func TestConfig_Succes(t *testing.T) {
 
 func TestConfig_Failures(t *testing.T) {
 	t.Parallel()
-	_, certFile, keyFile := testtls.CreateTestTLSCertificates(t.TempDir())
+	caFile, certFile, keyFile := testtls.CreateTestTLSCertificates(t.TempDir())
 
 	tests := []struct {
 		description   string
@@ -110,6 +111,69 @@ func TestConfig_Failures(t *testing.T) {
 			}),
 			routes:        []config.Route{},
 			expectedError: errors.New("server TLS cert file at path \"/path/does/not/exist\" does not exist"),
+		}, {
+			description: "fails with hidden protected routes without documentation authentication",
+			config: buildTestServerConfig(func(ic *config.Server) {
+				ic.Documentation.HideProtected = true
+			}),
+			routes:        []config.Route{},
+			expectedError: errors.New("documentation authentication is required when hiding protected routes"),
+		},
+		{
+			description: "fails with unknown documentation authentication provider",
+			config: buildTestServerConfig(func(ic *config.Server) {
+				ic.Documentation.HideProtected = true
+				ic.Documentation.Authentication = "sso"
+			}),
+			routes:        []config.Route{},
+			expectedError: errors.New("documentation authentication provider \"sso\" is not defined"),
+		},
+		{
+			description: "fails with client auth without client CA",
+			config: buildTestServerConfig(func(ic *config.Server) {
+				ic.TLS = config.ServerTLSConfig{
+					Cert:       certFile,
+					Key:        keyFile,
+					ClientAuth: "require",
+				}
+			}),
+			routes:        []config.Route{},
+			expectedError: errors.New("server TLS client CA is required when client auth is provided"),
+		},
+		{
+			description: "fails with client CA without TLS",
+			config: buildTestServerConfig(func(ic *config.Server) {
+				ic.TLS = config.ServerTLSConfig{
+					ClientCA: caFile,
+				}
+			}),
+			routes:        []config.Route{},
+			expectedError: errors.New("server TLS cert and key are required when client CA is provided"),
+		},
+		{
+			description: "fails with invalid client auth",
+			config: buildTestServerConfig(func(ic *config.Server) {
+				ic.TLS = config.ServerTLSConfig{
+					Cert:       certFile,
+					Key:        keyFile,
+					ClientCA:   caFile,
+					ClientAuth: "sometimes",
+				}
+			}),
+			routes:        []config.Route{},
+			expectedError: errors.New("server TLS client auth must be either \"require\" or \"optional\" but was \"sometimes\""),
+		},
+		{
+			description: "fails with client CA without certificates",
+			config: buildTestServerConfig(func(ic *config.Server) {
+				ic.TLS = config.ServerTLSConfig{
+					Cert:     certFile,
+					Key:      keyFile,
+					ClientCA: keyFile,
+				}
+			}),
+			routes:        []config.Route{},
+			expectedError: fmt.Errorf("server TLS client CA file at path %q contains no PEM encoded certificates", keyFile),
 		},
 	}
 
@@ -117,7 +181,7 @@ func TestConfig_Failures(t *testing.T) {
 		t.Run(test.description, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := server.NewConfig(test.config, test.routes)
+			_, err := server.NewConfig(config.Config{Server: test.config, Routes: test.routes}, nil)
 			if err == nil {
 				t.Fatalf("expected create server config to fail but got nil")
 			}
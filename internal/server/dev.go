@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/slightly-inconvenient/murl/internal/route"
+)
+
+// DevReloadPath is the path the live reload script polls for the version of the loaded configuration.
+const DevReloadPath = "/_murl/dev/version"
+
+var liveReloadScript = template.Must(template.New("reload").Parse(`<script>
+(function () {
+  var version = "{{ . }}";
+  setInterval(function () {
+    fetch("` + DevReloadPath + `", { cache: "no-store" })
+      .then(function (resp) { return resp.text(); })
+      .then(function (current) { if (current !== version) { location.reload(); } })
+      .catch(function () {});
+  }, 500);
+})();
+</script>
+`))
+
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>murl - invalid configuration</title>
+<style>
+body { margin: 0; font-family: sans-serif; background: #1e1e1e; color: #eee; }
+main { max-width: 60rem; margin: 3rem auto; padding: 0 1rem; }
+h1 { color: #ff6b6b; font-size: 1.4rem; }
+pre { background: #2d2d2d; padding: 1rem; white-space: pre-wrap; word-break: break-word; border-left: 4px solid #ff6b6b; }
+</style>
+</head>
+<body>
+<main>
+<h1>The configuration is invalid</h1>
+<p>The page reloads once the configuration is fixed.</p>
+<pre>{{ .Error }}</pre>
+</main>
+{{ .Script }}
+</body>
+</html>
+`))
+
+// DevServer serves the routes and documentation of the most recently loaded configuration for local authoring.
+// A live reload script is injected into the documentation page so the browser refreshes whenever a new configuration is loaded.
+// If the loaded configuration is invalid, an error page describing it is served for every request instead.
+// TLS and client authentication settings of the configuration are ignored and the server is always served over plain HTTP.
+type DevServer struct {
+	mu      sync.RWMutex
+	version int
+	handler http.Handler
+}
+
+// NewDevServer creates a development server serving the error page until a configuration is loaded.
+func NewDevServer() *DevServer {
+	server := &DevServer{}
+	server.Load(Config{}, nil, errors.New("no configuration has been loaded yet"))
+
+	return server
+}
+
+// Load replaces the served configuration and handlers. If err is not nil, the error page describing it is served instead.
+func (s *DevServer) Load(config Config, handlers []route.Handler, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	script := renderLiveReloadScript(s.version)
+
+	if err == nil && !config.valid {
+		err = errors.New("server config has not been validated - create the config using NewServerConfig")
+	}
+	if err != nil {
+		s.handler = createErrorPageHandler(err, script)
+		return
+	}
+
+	config.documentation.content = injectLiveReloadScript(config.documentation.content, script)
+	if config.documentation.publicContent != nil {
+		config.documentation.publicContent = injectLiveReloadScript(config.documentation.publicContent, script)
+	}
+	s.handler = newMux(config, handlers)
+}
+
+// Version returns the version of the loaded configuration. The version increases with every load.
+func (s *DevServer) Version() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.version
+}
+
+func (s *DevServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	version, handler := s.version, s.handler
+	s.mu.RUnlock()
+
+	if r.URL.Path == DevReloadPath {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte(strconv.Itoa(version)))
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// Run serves on the address until the context is done.
+func (s *DevServer) Run(ctx context.Context, address string) error {
+	return listenAndServe(ctx, &http.Server{Addr: address, Handler: s}, TLSConfig{})
+}
+
+func renderLiveReloadScript(version int) []byte {
+	buf := bytes.Buffer{}
+	if err := liveReloadScript.Execute(&buf, version); err != nil {
+		panic(fmt.Errorf("failed to render live reload script: %w", err))
+	}
+
+	return buf.Bytes()
+}
+
+// injectLiveReloadScript inserts the script before the closing body tag of the page or appends it if the page has none.
+func injectLiveReloadScript(page []byte, script []byte) []byte {
+	idx := bytes.LastIndex(page, []byte("</body>"))
+	if idx < 0 {
+		return append(bytes.Clone(page), script...)
+	}
+
+	result := make([]byte, 0, len(page)+len(script))
+	result = append(result, page[:idx]...)
+	result = append(result, script...)
+	return append(result, page[idx:]...)
+}
+
+func createErrorPageHandler(err error, script []byte) http.HandlerFunc {
+	buf := bytes.Buffer{}
+	if renderErr := errorPage.Execute(&buf, struct {
+		Error  string
+		Script template.HTML
+	}{
+		Error:  err.Error(),
+		Script: template.HTML(script),
+	}); renderErr != nil {
+		panic(fmt.Errorf("failed to render error page: %w", renderErr))
+	}
+	content := buf.Bytes()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write(content)
+	}
+}
@@ -0,0 +1,104 @@
+package server_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slightly-inconvenient/murl/internal/config"
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/slightly-inconvenient/murl/internal/server"
+)
+
+func TestDevServer(t *testing.T) {
+	t.Parallel()
+
+	conf := config.Config{
+		Server: buildTestServerConfig(),
+		Routes: []config.Route{
+			{
+				Path:          "/example",
+				Documentation: config.RouteDocumentation{Title: "Example route"},
+				Redirect:      config.RouteRedirect{URL: "https://example.com"},
+			},
+		},
+	}
+	routes, err := route.NewRoutes(conf, route.Environment{})
+	if err != nil {
+		t.Fatalf("failed to create routes: %v", err)
+	}
+	serverConfig, err := server.NewConfig(conf, routes)
+	if err != nil {
+		t.Fatalf("failed to create server config: %v", err)
+	}
+
+	get := func(t *testing.T, handler http.Handler, path string) (*http.Response, string) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		resp := recorder.Result()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	t.Run("serves the error page until a config is loaded", func(t *testing.T) {
+		t.Parallel()
+		dev := server.NewDevServer()
+
+		resp, body := get(t, dev, "/example")
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("expected status code to be 500 but got %s", resp.Status)
+		}
+		if !strings.Contains(body, "no configuration has been loaded yet") || !strings.Contains(body, server.DevReloadPath) {
+			t.Fatalf("expected error page with live reload script but got %q", body)
+		}
+	})
+
+	t.Run("serves routes and injects the live reload script into the documentation", func(t *testing.T) {
+		t.Parallel()
+		dev := server.NewDevServer()
+		dev.Load(serverConfig, route.NewHandlers(routes), nil)
+
+		resp, _ := get(t, dev, "/example")
+		if resp.StatusCode != http.StatusTemporaryRedirect || resp.Header.Get("Location") != "https://example.com" {
+			t.Fatalf("expected redirect to https://example.com but got %s to %q", resp.Status, resp.Header.Get("Location"))
+		}
+
+		resp, body := get(t, dev, "/")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status code to be 200 but got %s", resp.Status)
+		}
+		scriptIdx := strings.Index(body, server.DevReloadPath)
+		if scriptIdx < 0 || scriptIdx > strings.LastIndex(body, "</body>") || !strings.Contains(body, "Example route") {
+			t.Fatalf("expected documentation with live reload script before the closing body tag but got %q", body)
+		}
+
+		_, version := get(t, dev, server.DevReloadPath)
+		if version != "2" {
+			t.Fatalf("expected version to be 2 but got %q", version)
+		}
+	})
+
+	t.Run("replaces the routes with the escaped error of an invalid config", func(t *testing.T) {
+		t.Parallel()
+		dev := server.NewDevServer()
+		dev.Load(serverConfig, route.NewHandlers(routes), nil)
+		dev.Load(server.Config{}, nil, errors.New("invalid routes: route <b> is broken"))
+
+		resp, body := get(t, dev, "/example")
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("expected status code to be 500 but got %s", resp.Status)
+		}
+		if !strings.Contains(body, "route &lt;b&gt; is broken") {
+			t.Fatalf("expected error page with escaped error but got %q", body)
+		}
+
+		_, version := get(t, dev, server.DevReloadPath)
+		if version != "3" || dev.Version() != 3 {
+			t.Fatalf("expected version to be 3 but got %q", version)
+		}
+	})
+}
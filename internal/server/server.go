@@ -2,9 +2,12 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/slightly-inconvenient/murl/internal/route"
 )
@@ -14,17 +17,33 @@ func Run(ctx context.Context, config Config, handlers []route.Handler) error {
 		panic(errors.New("server config has not been validated - create the config using NewServerConfig"))
 	}
 
+	server := &http.Server{
+		Addr:    config.address,
+		Handler: newMux(config, handlers),
+	}
+	if config.tls.clientCAs != nil {
+		server.TLSConfig = &tls.Config{
+			ClientCAs:  config.tls.clientCAs,
+			ClientAuth: config.tls.clientAuth,
+		}
+	}
+
+	return listenAndServe(ctx, server, config.tls)
+}
+
+// newMux returns the mux serving the documentation and the route handlers.
+func newMux(config Config, handlers []route.Handler) *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET "+config.documentation.path, createDocsHandler(config.documentation.content))
+	mux.HandleFunc("GET "+config.documentation.path, createDocsHandler(config.documentation))
 	for _, handler := range handlers {
-		mux.HandleFunc(handler.Route(), handler.Handler())
+		mux.HandleFunc(handler.Route(), withAccessLog(handler.Handler()))
 	}
 
-	server := &http.Server{
-		Addr:    config.address,
-		Handler: mux,
-	}
+	return mux
+}
 
+// listenAndServe serves until the context is done and shuts the server down gracefully.
+func listenAndServe(ctx context.Context, server *http.Server, tls TLSConfig) error {
 	closed := make(chan struct{})
 
 	go func() {
@@ -33,11 +52,11 @@ func Run(ctx context.Context, config Config, handlers []route.Handler) error {
 		close(closed)
 	}()
 
-	fmt.Println("Starting server on", config.address)
+	fmt.Println("Starting server on", server.Addr)
 
 	result := error(nil)
-	if config.tls.cert != "" && config.tls.key != "" {
-		result = server.ListenAndServeTLS(config.tls.cert, config.tls.key)
+	if tls.cert != "" && tls.key != "" {
+		result = server.ListenAndServeTLS(tls.cert, tls.key)
 	} else {
 		result = server.ListenAndServe()
 	}
@@ -51,9 +70,44 @@ func Run(ctx context.Context, config Config, handlers []route.Handler) error {
 	return result
 }
 
-func createDocsHandler(documentation []byte) http.HandlerFunc {
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func withAccessLog(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, requestLog := route.WithRequestLog(r.Context())
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		handler(recorder, r.WithContext(ctx))
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.status),
+			slog.Duration("duration", time.Since(start)),
+		}
+		slog.LogAttrs(ctx, slog.LevelInfo, "request", append(attrs, requestLog.Attrs()...)...)
+	}
+}
+
+func createDocsHandler(documentation DocumentationConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		content := documentation.content
+		if documentation.authenticator != nil {
+			if _, ok := documentation.authenticator.Authenticate(r); !ok {
+				content = documentation.publicContent
+			}
+		}
+
 		w.Header().Set("Content-Type", "text/html")
-		_, _ = w.Write(documentation)
+		_, _ = w.Write(content)
 	}
 }
@@ -44,24 +44,33 @@ func TestRun(t *testing.T) {
 		}
 	})
 
-	caFile, certFile, keyFile := testtls.CreateTestTLSCertificates(t.TempDir())
-	httpClient := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: func() *x509.CertPool {
-					caPool := x509.NewCertPool()
-					caBytes, _ := os.ReadFile(caFile)
-					if !caPool.AppendCertsFromPEM(caBytes) {
-						panic(errors.New("failed to append client CA certificate"))
-					}
-					return caPool
-				}(),
+	caFile, certFile, keyFile, clientCertFile, clientKeyFile := testtls.CreateTestMTLSCertificates(t.TempDir())
+	createHTTPClient := func(certificates ...tls.Certificate) *http.Client {
+		return &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
 			},
-		},
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs: func() *x509.CertPool {
+						caPool := x509.NewCertPool()
+						caBytes, _ := os.ReadFile(caFile)
+						if !caPool.AppendCertsFromPEM(caBytes) {
+							panic(errors.New("failed to append client CA certificate"))
+						}
+						return caPool
+					}(),
+					Certificates: certificates,
+				},
+			},
+		}
 	}
+	httpClient := createHTTPClient()
+	clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	if err != nil {
+		t.Fatalf("failed to load client certificate: %v", err)
+	}
+	mtlsHTTPClient := createHTTPClient(clientCert)
 
 	checkDocs := func(expectedContent string) func(t *testing.T, resp *http.Response) {
 		return func(t *testing.T, resp *http.Response) {
@@ -86,11 +95,14 @@ func TestRun(t *testing.T) {
 	}
 
 	tests := []struct {
-		description string
-		config      config.Server
-		routes      []config.Route
-		requestPath string
-		check       func(t *testing.T, resp *http.Response)
+		description    string
+		config         config.Server
+		authentication map[string]config.AuthProvider
+		routes         []config.Route
+		groups         []config.RouteGroup
+		client         *http.Client
+		requestPath    string
+		check          func(t *testing.T, resp *http.Response)
 	}{
 		{
 			description: "serves routes without TLS",
@@ -126,6 +138,73 @@ func TestRun(t *testing.T) {
 				}
 			},
 		},
+		{
+			description: "serves routes with mutual TLS",
+			config: config.Server{
+				Address: "localhost:8444",
+				TLS: config.ServerTLSConfig{
+					Cert:     certFile,
+					Key:      keyFile,
+					ClientCA: caFile,
+				},
+			},
+			routes: []config.Route{
+				{
+					Path: "/test",
+					Params: map[string]string{
+						"cn": `{{.GetClientCert.CommonName}}`,
+					},
+					Checks: []config.RouteCheck{
+						{
+							Expr:  `"Test Clients" in tls.organizations && "client@example.com" in tls.sans`,
+							Error: "unexpected client certificate {{.tls.subject}}",
+						},
+					},
+					Redirect: config.RouteRedirect{URL: "http://localhost:8080/{{.cn}}"},
+				},
+			},
+			client:      mtlsHTTPClient,
+			requestPath: "/test",
+			check: func(t *testing.T, resp *http.Response) {
+				if resp.StatusCode != http.StatusTemporaryRedirect {
+					content, _ := io.ReadAll(resp.Body)
+					t.Fatalf("expected status code to be 307 Temporary Redirect but got %s (body: %s)", resp.Status, content)
+				}
+				if resp.Header.Get("Location") != "http://localhost:8080/test_client_cert" {
+					t.Fatalf("expected redirect to client certificate common name but got %s", resp.Header.Get("Location"))
+				}
+			},
+		},
+		{
+			description: "serves routes with optional mutual TLS",
+			config: config.Server{
+				Address: "localhost:8445",
+				TLS: config.ServerTLSConfig{
+					Cert:       certFile,
+					Key:        keyFile,
+					ClientCA:   caFile,
+					ClientAuth: "optional",
+				},
+			},
+			routes: []config.Route{
+				{
+					Path: "/test",
+					Checks: []config.RouteCheck{
+						{
+							Expr:  `tls.verified`,
+							Error: "client certificate required",
+						},
+					},
+					Redirect: config.RouteRedirect{URL: "http://localhost:8080/test2"},
+				},
+			},
+			requestPath: "/test",
+			check: func(t *testing.T, resp *http.Response) {
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Fatalf("expected status code to be 400 Bad Request but got %s", resp.Status)
+				}
+			},
+		},
 		{
 			description: "serves docs from default path",
 			config: config.Server{
@@ -207,6 +286,128 @@ including default routes template
 			requestPath: "",
 			check:       checkDocs("<!DOCTYPE html>\n<html>\n<body>\n<h1 id=\"available-routes\">Available Routes</h1>\n<h2 id=\"test-route\">Test Route</h2>\n<p>A test route</p>\n\n</body>\n</html>"),
 		},
+		{
+			description: "serves time windowed route docs",
+			config: config.Server{
+				Address: "localhost:8087",
+			},
+			routes: []config.Route{
+				{
+					Path:        "/test",
+					ActiveFrom:  "2024-06-01T09:00:00+02:00",
+					ActiveUntil: "2024-06-01T10:00:00+02:00",
+					Documentation: config.RouteDocumentation{
+						Title: "Test Route",
+					},
+					Redirect: config.RouteRedirect{URL: "http://localhost:8080/test2"},
+				},
+			},
+			requestPath: "",
+			check:       checkDocs("<!DOCTYPE html>\n<html>\n<body>\n<h1 id=\"available-routes\">Available Routes</h1>\n<h2 id=\"test-route\">Test Route</h2>\n<p><em>Active from 2024-06-01T09:00:00+02:00 until 2024-06-01T10:00:00+02:00</em></p>\n\n</body>\n</html>"),
+		},
+		{
+			description: "serves grouped route docs",
+			config: config.Server{
+				Address: "localhost:8089",
+			},
+			routes: []config.Route{
+				{
+					Path:          "/test",
+					Documentation: config.RouteDocumentation{Title: "Test Route"},
+					Redirect:      config.RouteRedirect{URL: "http://localhost:8080/test2"},
+				},
+			},
+			authentication: map[string]config.AuthProvider{
+				"sso": {Header: config.AuthHeader{User: "X-Forwarded-User"}},
+			},
+			groups: []config.RouteGroup{
+				{
+					Prefix: "/jira",
+					Documentation: config.RouteDocumentation{
+						Title:       "Jira",
+						Description: "Shortcuts to the issue tracker",
+					},
+					Environment:    config.RouteEnvironment{Allowlist: []string{"JIRA_HOST"}},
+					Params:         map[string]string{"host": `{{.GetEnv "JIRA_HOST"}}`},
+					Checks:         []config.RouteCheck{{Expr: `host != ""`, Error: "host is required"}},
+					Authentication: "sso",
+					IPAccess:       config.IPAccess{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.1"}},
+					Routes: []config.Route{
+						{
+							Path:          "/browse/{id}",
+							Documentation: config.RouteDocumentation{Title: "Browse Issue"},
+							Params:        map[string]string{"id": `{{.GetPath "id"}}`},
+							Redirect:      config.RouteRedirect{URL: "https://{{.host}}/browse/{{.id}}"},
+						},
+					},
+				},
+			},
+			requestPath: "",
+			check:       checkDocs("<!DOCTYPE html>\n<html>\n<body>\n<h1 id=\"available-routes\">Available Routes</h1>\n<h2 id=\"test-route\">Test Route</h2>\n<h2 id=\"jira\">Jira</h2>\n<p>Shortcuts to the issue tracker</p>\n<p><em>Routes in this group are served under <code>/jira</code></em></p>\n<p>Shared environment variables:</p>\n<ul>\n<li><code>JIRA_HOST</code></li>\n</ul>\n<p>Shared params:</p>\n<ul>\n<li><code>host</code></li>\n</ul>\n<p>Shared checks:</p>\n<ul>\n<li><code>host != &quot;&quot;</code></li>\n</ul>\n<p>Requires authentication with <code>sso</code></p>\n<p>Shared client address restrictions:</p>\n<ul>\n<li>allow <code>10.0.0.0/8</code></li>\n<li>deny <code>10.0.0.1</code></li>\n</ul>\n<h3 id=\"browse-issue\">Browse Issue</h3>\n\n</body>\n</html>"),
+		},
+		{
+			description: "serves docs without protected routes to unauthenticated requests",
+			config: config.Server{
+				Address: "localhost:8088",
+				Documentation: config.ServerDocumentationConfig{
+					HideProtected:  true,
+					Authentication: "sso",
+				},
+			},
+			authentication: map[string]config.AuthProvider{
+				"sso": {Header: config.AuthHeader{User: "X-Forwarded-User"}},
+			},
+			routes: []config.Route{
+				{
+					Path:          "/public",
+					Documentation: config.RouteDocumentation{Title: "Public Route"},
+					Redirect:      config.RouteRedirect{URL: "http://localhost:8080/test2"},
+				},
+				{
+					Path:           "/protected",
+					Authentication: "sso",
+					Documentation:  config.RouteDocumentation{Title: "Protected Route"},
+					Redirect:       config.RouteRedirect{URL: "http://localhost:8080/test2"},
+				},
+			},
+			groups: []config.RouteGroup{
+				{
+					Prefix:         "/admin",
+					Documentation:  config.RouteDocumentation{Title: "Admin"},
+					Authentication: "sso",
+					Routes: []config.Route{
+						{
+							Path:          "/users",
+							Documentation: config.RouteDocumentation{Title: "Users"},
+							Redirect:      config.RouteRedirect{URL: "http://localhost:8080/users"},
+						},
+					},
+				},
+			},
+			requestPath: "",
+			check:       checkDocs("<!DOCTYPE html>\n<html>\n<body>\n<h1 id=\"available-routes\">Available Routes</h1>\n<h2 id=\"public-route\">Public Route</h2>\n\n</body>\n</html>"),
+		},
+		{
+			description: "serves deprecated route docs",
+			config: config.Server{
+				Address: "localhost:8086",
+			},
+			routes: []config.Route{
+				{
+					Path:    "/test",
+					Aliases: []string{"/test-alias"},
+					Deprecations: []config.RouteDeprecation{
+						{Path: "/test-alias", Message: "Use /test instead", Sunset: "2030-01-01T00:00:00Z"},
+					},
+					Documentation: config.RouteDocumentation{
+						Title: "Test Route",
+					},
+					Redirect: config.RouteRedirect{URL: "http://localhost:8080/test2"},
+				},
+			},
+			requestPath: "",
+			check:       checkDocs("<!DOCTYPE html>\n<html>\n<body>\n<h1 id=\"available-routes\">Available Routes</h1>\n<h2 id=\"test-route\">Test Route</h2>\n<blockquote>\n<p><strong>Deprecated:</strong> <code>/test-alias</code> stops working on 2030-01-01T00:00:00Z. Use /test instead</p>\n</blockquote>\n\n</body>\n</html>"),
+		},
 	}
 
 	for _, test := range tests {
@@ -216,13 +417,13 @@ including default routes template
 			ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancelCtx()
 
-			routes, err := route.NewRoutes(test.routes)
+			routes, err := route.NewRoutes(config.Config{Server: test.config, Authentication: test.authentication, Routes: test.routes, Groups: test.groups}, route.Environment{})
 			if err != nil {
 				t.Fatalf("expected create routes to succeed but got error: %s", err)
 			}
 
 			errCh := make(chan error)
-			config, err := server.NewConfig(test.config, test.routes)
+			config, err := server.NewConfig(config.Config{Server: test.config, Authentication: test.authentication, Routes: test.routes, Groups: test.groups}, routes)
 			if err != nil {
 				t.Fatalf("failed to create test server config: %v", err)
 			}
@@ -244,7 +445,12 @@ including default routes template
 							baseURL = strings.Replace(baseURL, "http:", "https:", 1)
 						}
 
-						resp, err := httpClient.Get(baseURL + test.requestPath)
+						client := httpClient
+						if test.client != nil {
+							client = test.client
+						}
+
+						resp, err := client.Get(baseURL + test.requestPath)
 						if err == nil {
 							test.check(t, resp)
 							cancelCtx()
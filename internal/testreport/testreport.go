@@ -0,0 +1,519 @@
+package testreport
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/route"
+)
+
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// Formats are the supported report formats.
+var Formats = []string{FormatText, FormatJSON, FormatJUnit}
+
+// Write writes the report of the test results in the given format.
+func Write(w io.Writer, format string, results []route.TestResult) error {
+	switch format {
+	case FormatText:
+		return writeText(w, results, true)
+	case FormatJSON:
+		return writeJSON(w, results)
+	case FormatJUnit:
+		return writeJUnit(w, results)
+	default:
+		return fmt.Errorf("unsupported report format %q (supported are %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Failed returns the number of failed tests.
+func Failed(results []route.TestResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Failure != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Skipped returns the number of tests which were not run.
+func Skipped(results []route.TestResult) int {
+	skipped := 0
+	for _, result := range results {
+		if result.Skipped != "" {
+			skipped++
+		}
+	}
+
+	return skipped
+}
+
+// summary returns the summary line of the test results. Skipped tests are only mentioned if there are any.
+func summary(results []route.TestResult) string {
+	failed, skipped := Failed(results), Skipped(results)
+	result := fmt.Sprintf("%d tests, %d passed, %d failed", len(results), len(results)-failed-skipped, failed)
+	if skipped > 0 {
+		result += fmt.Sprintf(", %d skipped", skipped)
+	}
+
+	return result
+}
+
+// WriteFailures writes the text report of only the failed tests followed by the summary of all tests.
+func WriteFailures(w io.Writer, results []route.TestResult) error {
+	return writeText(w, results, false)
+}
+
+// writeText writes the text report of the test results. Passed and skipped tests are only listed if verbose.
+func writeText(w io.Writer, results []route.TestResult, verbose bool) error {
+	builder := &strings.Builder{}
+	for _, result := range results {
+		if !verbose && result.Failure == nil {
+			continue
+		}
+
+		if result.Skipped != "" {
+			fmt.Fprintf(builder, "SKIP %s %s %s\n    %s\n", result.Name(), result.Method, result.URL, result.Skipped)
+			continue
+		}
+
+		status := "PASS"
+		if !result.Passed() {
+			status = "FAIL"
+		}
+
+		fmt.Fprintf(builder, "%s %s %s %s (%s)\n", status, result.Name(), result.Method, result.URL, result.Duration.Round(time.Microsecond))
+		if result.Passed() {
+			continue
+		}
+
+		fmt.Fprintf(builder, "    %s\n", result.Failure.Message)
+		if result.Row != nil {
+			fmt.Fprintf(builder, "    row:      %s\n", rowValues(result.Row))
+		}
+		if result.Failure.Expected != "" {
+			fmt.Fprintf(builder, "    expected: %q\n", result.Failure.Expected)
+		}
+		if result.Failure.Actual != "" {
+			fmt.Fprintf(builder, "    actual:   %q\n", result.Failure.Actual)
+		}
+	}
+
+	fmt.Fprintln(builder, summary(results))
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+type jsonReport struct {
+	Tests   int          `json:"tests"`
+	Passed  int          `json:"passed"`
+	Failed  int          `json:"failed"`
+	Skipped int          `json:"skipped,omitempty"`
+	Results []jsonResult `json:"results"`
+}
+
+type jsonResult struct {
+	RouteIndex int          `json:"route_index"`
+	Path       string       `json:"path"`
+	Title      string       `json:"title,omitempty"`
+	TestIndex  int          `json:"test_index"`
+	Row        *jsonRow     `json:"row,omitempty"`
+	Method     string       `json:"method"`
+	URL        string       `json:"url"`
+	Passed     bool         `json:"passed"`
+	DurationMS float64      `json:"duration_ms"`
+	Failure    *jsonFailure `json:"failure,omitempty"`
+	Skipped    string       `json:"skipped,omitempty"`
+}
+
+type jsonRow struct {
+	Index  int               `json:"index"`
+	Values map[string]string `json:"values"`
+}
+
+type jsonFailure struct {
+	Message  string `json:"message"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func writeJSON(w io.Writer, results []route.TestResult) error {
+	failed, skipped := Failed(results), Skipped(results)
+	report := jsonReport{
+		Tests:   len(results),
+		Passed:  len(results) - failed - skipped,
+		Failed:  failed,
+		Skipped: skipped,
+		Results: make([]jsonResult, 0, len(results)),
+	}
+
+	for _, result := range results {
+		resultJSON := jsonResult{
+			RouteIndex: result.RouteIndex,
+			Path:       result.Path,
+			Title:      result.Title,
+			TestIndex:  result.TestIndex,
+			Method:     result.Method,
+			URL:        result.URL,
+			Passed:     result.Passed(),
+			DurationMS: float64(result.Duration) / float64(time.Millisecond),
+			Skipped:    result.Skipped,
+		}
+		if result.Row != nil {
+			resultJSON.Row = &jsonRow{Index: result.Row.Index, Values: result.Row.Values}
+		}
+		if result.Failure != nil {
+			resultJSON.Failure = &jsonFailure{
+				Message:  result.Failure.Message,
+				Expected: result.Failure.Expected,
+				Actual:   result.Failure.Actual,
+			}
+		}
+		report.Results = append(report.Results, resultJSON)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr,omitempty"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr,omitempty"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes the results as JUnit XML with a test suite per route.
+func writeJUnit(w io.Writer, results []route.TestResult) error {
+	report := junitTestSuites{Name: "murl"}
+	var total time.Duration
+	suiteDurations := []time.Duration{}
+	for _, result := range results {
+		if len(report.Suites) == 0 || report.Suites[len(report.Suites)-1].Name != suiteName(result) {
+			report.Suites = append(report.Suites, junitTestSuite{Name: suiteName(result)})
+			suiteDurations = append(suiteDurations, 0)
+		}
+		suite := &report.Suites[len(report.Suites)-1]
+		suiteDurations[len(suiteDurations)-1] += result.Duration
+
+		testCase := junitTestCase{
+			Name:      caseName(result),
+			ClassName: result.Path,
+			Time:      junitSeconds(result.Duration),
+		}
+		if result.Failure != nil {
+			content := []string{result.Failure.Message}
+			if result.Row != nil {
+				content = append(content, "row: "+rowValues(result.Row))
+			}
+			if result.Failure.Expected != "" {
+				content = append(content, fmt.Sprintf("expected: %q", result.Failure.Expected))
+			}
+			if result.Failure.Actual != "" {
+				content = append(content, fmt.Sprintf("actual: %q", result.Failure.Actual))
+			}
+			testCase.Failure = &junitFailure{
+				Message: result.Failure.Message,
+				Type:    "AssertionError",
+				Content: strings.Join(content, "\n"),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		if result.Skipped != "" {
+			testCase.Skipped = &junitSkipped{Message: result.Skipped}
+			suite.Skipped++
+			report.Skipped++
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		report.Tests++
+		total += result.Duration
+	}
+
+	for idx, duration := range suiteDurations {
+		report.Suites[idx].Time = junitSeconds(duration)
+	}
+	report.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// caseName returns the name of the test case of the result, e.g. test [0] row [1] GET /issue/1.
+func caseName(result route.TestResult) string {
+	name := fmt.Sprintf("test [%d]", result.TestIndex)
+	if result.Row != nil {
+		name += fmt.Sprintf(" row [%d]", result.Row.Index)
+	}
+
+	return fmt.Sprintf("%s %s %s", name, result.Method, result.URL)
+}
+
+// rowValues returns the variables of the row sorted by name, e.g. id="1", lang="en".
+func rowValues(row *route.TestRow) string {
+	values := make([]string, 0, len(row.Values))
+	for _, key := range slices.Sorted(maps.Keys(row.Values)) {
+		values = append(values, fmt.Sprintf("%s=%q", key, row.Values[key]))
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// suiteName returns the name of the test suite of the route the result belongs to.
+func suiteName(result route.TestResult) string {
+	name := fmt.Sprintf("route [%d] %s", result.RouteIndex, result.Path)
+	if result.Title != "" {
+		name += " (" + result.Title + ")"
+	}
+
+	return name
+}
+
+func junitSeconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// CoveragePercent returns the percentage of route items exercised by at least one test.
+// Routes without items are fully covered.
+func CoveragePercent(coverage []route.RouteCoverage) float64 {
+	covered, total := 0, 0
+	for _, routeCoverage := range coverage {
+		covered += routeCoverage.Covered()
+		total += len(routeCoverage.Items)
+	}
+
+	return percent(covered, total)
+}
+
+// WriteCoverage writes the coverage of every route listing the items not exercised by any test.
+func WriteCoverage(w io.Writer, coverage []route.RouteCoverage) error {
+	builder := &strings.Builder{}
+	covered, total := 0, 0
+	for _, routeCoverage := range coverage {
+		routeCovered := routeCoverage.Covered()
+		fmt.Fprintf(builder, "coverage for route %q at index [%d]: %d of %d items (%.1f%%)\n", routeCoverage.Path, routeCoverage.RouteIndex, routeCovered, len(routeCoverage.Items), percent(routeCovered, len(routeCoverage.Items)))
+		for _, item := range routeCoverage.Items {
+			if !item.Covered {
+				fmt.Fprintf(builder, "    missing %s\n", item.Name)
+			}
+		}
+
+		covered += routeCovered
+		total += len(routeCoverage.Items)
+	}
+	fmt.Fprintf(builder, "total coverage: %d of %d items (%.1f%%)\n", covered, total, percent(covered, total))
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func percent(covered int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+
+	return float64(covered) / float64(total) * 100
+}
+
+// Survived returns the number of mutants run and not killed by any test.
+func Survived(mutants []route.Mutant) int {
+	survived := 0
+	for _, mutant := range mutants {
+		if !mutant.Killed && !mutant.Skipped {
+			survived++
+		}
+	}
+
+	return survived
+}
+
+// WriteMutations writes the number of killed mutants of every route with mutants listing the surviving mutants.
+// Routes whose mutants were skipped are flagged instead.
+func WriteMutations(w io.Writer, mutants []route.Mutant) error {
+	builder := &strings.Builder{}
+	skipped := 0
+	for start := 0; start < len(mutants); {
+		end := start
+		killed := 0
+		for end < len(mutants) && mutants[end].RouteIndex == mutants[start].RouteIndex {
+			if mutants[end].Killed {
+				killed++
+			}
+			end++
+		}
+
+		if mutants[start].Skipped {
+			skipped += end - start
+			fmt.Fprintf(builder, "mutants for route %q at index [%d]: %d skipped, tests fail against the unmutated route\n", mutants[start].Path, mutants[start].RouteIndex, end-start)
+			start = end
+			continue
+		}
+
+		fmt.Fprintf(builder, "mutants for route %q at index [%d]: %d of %d killed\n", mutants[start].Path, mutants[start].RouteIndex, killed, end-start)
+		for _, mutant := range mutants[start:end] {
+			if !mutant.Killed {
+				fmt.Fprintf(builder, "    survived %s\n", mutant.Description)
+			}
+		}
+		start = end
+	}
+	survived := Survived(mutants)
+	if skipped > 0 {
+		fmt.Fprintf(builder, "total mutants: %d of %d killed, %d survived, %d skipped\n", len(mutants)-survived-skipped, len(mutants)-skipped, survived, skipped)
+	} else {
+		fmt.Fprintf(builder, "total mutants: %d of %d killed, %d survived\n", len(mutants)-survived, len(mutants), survived)
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// WriteFindings writes the report of the lint findings in the given format.
+func WriteFindings(w io.Writer, format string, findings []route.LintFinding) error {
+	switch format {
+	case FormatText:
+		builder := &strings.Builder{}
+		for _, finding := range findings {
+			fmt.Fprintf(builder, "route %q at index [%d]: %s: %s\n", finding.Path, finding.RouteIndex, finding.Rule, finding.Message)
+		}
+		fmt.Fprintf(builder, "%d findings\n", len(findings))
+
+		_, err := io.WriteString(w, builder.String())
+		return err
+	case FormatJSON:
+		return writeFindingsJSON(w, findings)
+	case FormatJUnit:
+		return writeFindingsJUnit(w, findings)
+	default:
+		return fmt.Errorf("unsupported report format %q (supported are %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+type jsonFindingsReport struct {
+	Findings int           `json:"findings"`
+	Results  []jsonFinding `json:"results"`
+}
+
+type jsonFinding struct {
+	RouteIndex int    `json:"route_index"`
+	Path       string `json:"path"`
+	Title      string `json:"title,omitempty"`
+	Rule       string `json:"rule"`
+	Message    string `json:"message"`
+}
+
+func writeFindingsJSON(w io.Writer, findings []route.LintFinding) error {
+	report := jsonFindingsReport{
+		Findings: len(findings),
+		Results:  make([]jsonFinding, 0, len(findings)),
+	}
+	for _, finding := range findings {
+		report.Results = append(report.Results, jsonFinding{
+			RouteIndex: finding.RouteIndex,
+			Path:       finding.Path,
+			Title:      finding.Title,
+			Rule:       finding.Rule,
+			Message:    finding.Message,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// writeFindingsJUnit writes the findings as JUnit XML with a test suite per route with findings and a failed test case per finding.
+func writeFindingsJUnit(w io.Writer, findings []route.LintFinding) error {
+	report := junitTestSuites{Name: "murl lint", Time: junitSeconds(0)}
+	for _, finding := range findings {
+		name := suiteName(route.TestResult{RouteIndex: finding.RouteIndex, Path: finding.Path, Title: finding.Title})
+		if len(report.Suites) == 0 || report.Suites[len(report.Suites)-1].Name != name {
+			report.Suites = append(report.Suites, junitTestSuite{Name: name, Time: junitSeconds(0)})
+		}
+		suite := &report.Suites[len(report.Suites)-1]
+
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      finding.Rule,
+			ClassName: finding.Path,
+			Time:      junitSeconds(0),
+			Failure: &junitFailure{
+				Message: finding.Message,
+				Type:    "LintFinding",
+				Content: finding.Message,
+			},
+		})
+		suite.Tests++
+		suite.Failures++
+		report.Tests++
+		report.Failures++
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
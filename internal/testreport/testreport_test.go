@@ -0,0 +1,407 @@
+package testreport_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slightly-inconvenient/murl/internal/route"
+	"github.com/slightly-inconvenient/murl/internal/testreport"
+)
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	results := []route.TestResult{
+		{
+			RouteIndex: 0,
+			Path:       "/issue/{id}",
+			Title:      "Issue",
+			TestIndex:  0,
+			Method:     "GET",
+			URL:        "/issue/1",
+			Duration:   1500 * time.Microsecond,
+		},
+		{
+			RouteIndex: 0,
+			Path:       "/issue/{id}",
+			Title:      "Issue",
+			TestIndex:  1,
+			Method:     "GET",
+			URL:        "/issue/abc",
+			Duration:   500 * time.Microsecond,
+			Failure: &route.TestFailure{
+				Message:  "expected status 400 but got 307",
+				Expected: "400",
+				Actual:   "307",
+			},
+		},
+	}
+
+	tests := []struct {
+		description    string
+		format         string
+		expectedOutput string
+	}{
+		{
+			description: "writes text report",
+			format:      testreport.FormatText,
+			expectedOutput: `PASS test [0] for route "/issue/{id}" at index [0] GET /issue/1 (1.5ms)
+FAIL test [1] for route "/issue/{id}" at index [0] GET /issue/abc (500µs)
+    expected status 400 but got 307
+    expected: "400"
+    actual:   "307"
+2 tests, 1 passed, 1 failed
+`,
+		},
+		{
+			description: "writes json report",
+			format:      testreport.FormatJSON,
+			expectedOutput: `{
+  "tests": 2,
+  "passed": 1,
+  "failed": 1,
+  "results": [
+    {
+      "route_index": 0,
+      "path": "/issue/{id}",
+      "title": "Issue",
+      "test_index": 0,
+      "method": "GET",
+      "url": "/issue/1",
+      "passed": true,
+      "duration_ms": 1.5
+    },
+    {
+      "route_index": 0,
+      "path": "/issue/{id}",
+      "title": "Issue",
+      "test_index": 1,
+      "method": "GET",
+      "url": "/issue/abc",
+      "passed": false,
+      "duration_ms": 0.5,
+      "failure": {
+        "message": "expected status 400 but got 307",
+        "expected": "400",
+        "actual": "307"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			description: "writes junit report",
+			format:      testreport.FormatJUnit,
+			expectedOutput: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="murl" tests="2" failures="1" time="0.002">
+  <testsuite name="route [0] /issue/{id} (Issue)" tests="2" failures="1" time="0.002">
+    <testcase name="test [0] GET /issue/1" classname="/issue/{id}" time="0.002"></testcase>
+    <testcase name="test [1] GET /issue/abc" classname="/issue/{id}" time="0.001">
+      <failure message="expected status 400 but got 307" type="AssertionError">expected status 400 but got 307&#xA;expected: &#34;400&#34;&#xA;actual: &#34;307&#34;</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := &bytes.Buffer{}
+			if err := testreport.Write(buffer, test.format, results); err != nil {
+				t.Fatalf("expected write to succeed but got error: %v", err)
+			}
+
+			if buffer.String() != test.expectedOutput {
+				t.Fatalf("expected output\n%s\nbut got\n%s", test.expectedOutput, buffer.String())
+			}
+		})
+	}
+
+	t.Run("fails with unsupported format", func(t *testing.T) {
+		t.Parallel()
+		err := testreport.Write(&bytes.Buffer{}, "tap", results)
+		expectedError := errors.New("unsupported report format \"tap\" (supported are text, json, junit)")
+		if err == nil || err.Error() != expectedError.Error() {
+			t.Fatalf("expected error %q but got %v", expectedError, err)
+		}
+	})
+}
+
+func TestWrite_Skipped(t *testing.T) {
+	t.Parallel()
+
+	results := []route.TestResult{
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 0, Method: "GET", URL: "/issue/1", Duration: 1500 * time.Microsecond},
+		{RouteIndex: 0, Path: "/issue/{id}", TestIndex: 1, Method: "GET", URL: "/issue/2", Skipped: "test overrides the environment"},
+	}
+
+	tests := []struct {
+		description    string
+		format         string
+		expectedOutput string
+	}{
+		{
+			description: "writes skipped tests in text report",
+			format:      testreport.FormatText,
+			expectedOutput: `PASS test [0] for route "/issue/{id}" at index [0] GET /issue/1 (1.5ms)
+SKIP test [1] for route "/issue/{id}" at index [0] GET /issue/2
+    test overrides the environment
+2 tests, 1 passed, 0 failed, 1 skipped
+`,
+		},
+		{
+			description: "writes skipped tests in junit report",
+			format:      testreport.FormatJUnit,
+			expectedOutput: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="murl" tests="2" failures="0" skipped="1" time="0.002">
+  <testsuite name="route [0] /issue/{id}" tests="2" failures="0" skipped="1" time="0.002">
+    <testcase name="test [0] GET /issue/1" classname="/issue/{id}" time="0.002"></testcase>
+    <testcase name="test [1] GET /issue/2" classname="/issue/{id}" time="0.000">
+      <skipped message="test overrides the environment"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := &bytes.Buffer{}
+			if err := testreport.Write(buffer, test.format, results); err != nil {
+				t.Fatalf("expected write to succeed but got error: %v", err)
+			}
+
+			if buffer.String() != test.expectedOutput {
+				t.Fatalf("expected output\n%s\nbut got\n%s", test.expectedOutput, buffer.String())
+			}
+		})
+	}
+
+	if failed := testreport.Failed(results); failed != 0 {
+		t.Fatalf("expected skipped tests not to count as failed but got %d failed", failed)
+	}
+}
+
+func TestWrite_Row(t *testing.T) {
+	t.Parallel()
+
+	results := []route.TestResult{
+		{
+			RouteIndex: 0,
+			Path:       "/issue/{id}",
+			TestIndex:  1,
+			Row:        &route.TestRow{Index: 2, Values: map[string]string{"lang": "de", "id": "3"}},
+			Method:     "GET",
+			URL:        "/issue/3",
+			Duration:   500 * time.Microsecond,
+			Failure:    &route.TestFailure{Message: "expected status 400 but got 307"},
+		},
+	}
+
+	tests := []struct {
+		description    string
+		format         string
+		expectedOutput string
+	}{
+		{
+			description: "writes failing row in text report",
+			format:      testreport.FormatText,
+			expectedOutput: `FAIL test [1] row [2] for route "/issue/{id}" at index [0] GET /issue/3 (500µs)
+    expected status 400 but got 307
+    row:      id="3", lang="de"
+1 tests, 0 passed, 1 failed
+`,
+		},
+		{
+			description: "writes failing row in junit report",
+			format:      testreport.FormatJUnit,
+			expectedOutput: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="murl" tests="1" failures="1" time="0.001">
+  <testsuite name="route [0] /issue/{id}" tests="1" failures="1" time="0.001">
+    <testcase name="test [1] row [2] GET /issue/3" classname="/issue/{id}" time="0.001">
+      <failure message="expected status 400 but got 307" type="AssertionError">expected status 400 but got 307&#xA;row: id=&#34;3&#34;, lang=&#34;de&#34;</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := &bytes.Buffer{}
+			if err := testreport.Write(buffer, test.format, results); err != nil {
+				t.Fatalf("expected write to succeed but got error: %v", err)
+			}
+
+			if buffer.String() != test.expectedOutput {
+				t.Fatalf("expected output\n%s\nbut got\n%s", test.expectedOutput, buffer.String())
+			}
+		})
+	}
+}
+
+func TestWriteCoverage(t *testing.T) {
+	t.Parallel()
+
+	coverage := []route.RouteCoverage{
+		{
+			RouteIndex: 0,
+			Path:       "/issue/{id}",
+			Items: []route.CoverageItem{
+				{Name: `path "/issue/{id}"`, Covered: true},
+				{Name: `path "/i/{id}"`},
+				{Name: "check [0] passed", Covered: true},
+				{Name: "check [0] failed"},
+			},
+		},
+		{
+			RouteIndex: 1,
+			Path:       "/other",
+			Items: []route.CoverageItem{
+				{Name: `path "/other"`, Covered: true},
+			},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := testreport.WriteCoverage(buffer, coverage); err != nil {
+		t.Fatalf("expected write to succeed but got error: %v", err)
+	}
+
+	expectedOutput := `coverage for route "/issue/{id}" at index [0]: 2 of 4 items (50.0%)
+    missing path "/i/{id}"
+    missing check [0] failed
+coverage for route "/other" at index [1]: 1 of 1 items (100.0%)
+total coverage: 3 of 5 items (60.0%)
+`
+	if buffer.String() != expectedOutput {
+		t.Fatalf("expected output\n%s\nbut got\n%s", expectedOutput, buffer.String())
+	}
+
+	if percent := testreport.CoveragePercent(coverage); percent != 60 {
+		t.Fatalf("expected coverage of 60%% but got %v", percent)
+	}
+
+	if percent := testreport.CoveragePercent(nil); percent != 100 {
+		t.Fatalf("expected coverage without items to be 100%% but got %v", percent)
+	}
+}
+
+func TestWriteMutations(t *testing.T) {
+	t.Parallel()
+
+	mutants := []route.Mutant{
+		{RouteIndex: 0, Path: "/issue/{id}", Description: "negate check [0]", Killed: true},
+		{RouteIndex: 0, Path: "/issue/{id}", Description: "remove check [0]"},
+		{RouteIndex: 2, Path: "/other", Description: `remove literal "https://example.com/" from redirect url`, Killed: true},
+		{RouteIndex: 3, Path: "/broken", Description: "negate check [0]", Skipped: true},
+		{RouteIndex: 3, Path: "/broken", Description: "remove check [0]", Skipped: true},
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := testreport.WriteMutations(buffer, mutants); err != nil {
+		t.Fatalf("expected write to succeed but got error: %v", err)
+	}
+
+	expectedOutput := `mutants for route "/issue/{id}" at index [0]: 1 of 2 killed
+    survived remove check [0]
+mutants for route "/other" at index [2]: 1 of 1 killed
+mutants for route "/broken" at index [3]: 2 skipped, tests fail against the unmutated route
+total mutants: 2 of 3 killed, 1 survived, 2 skipped
+`
+	if buffer.String() != expectedOutput {
+		t.Fatalf("expected output\n%s\nbut got\n%s", expectedOutput, buffer.String())
+	}
+}
+
+func TestWriteFindings(t *testing.T) {
+	t.Parallel()
+
+	findings := []route.LintFinding{
+		{RouteIndex: 0, Path: "/issue/{id}", Title: "Issue", Rule: route.LintMissingTests, Message: "route has no tests"},
+		{RouteIndex: 2, Path: "/docs", Rule: route.LintUnusedParam, Message: `param "lang" is never used in checks, check errors or redirect templates`},
+	}
+
+	tests := []struct {
+		description    string
+		format         string
+		expectedOutput string
+	}{
+		{
+			description: "writes text report",
+			format:      testreport.FormatText,
+			expectedOutput: `route "/issue/{id}" at index [0]: missing-tests: route has no tests
+route "/docs" at index [2]: unused-param: param "lang" is never used in checks, check errors or redirect templates
+2 findings
+`,
+		},
+		{
+			description: "writes json report",
+			format:      testreport.FormatJSON,
+			expectedOutput: `{
+  "findings": 2,
+  "results": [
+    {
+      "route_index": 0,
+      "path": "/issue/{id}",
+      "title": "Issue",
+      "rule": "missing-tests",
+      "message": "route has no tests"
+    },
+    {
+      "route_index": 2,
+      "path": "/docs",
+      "rule": "unused-param",
+      "message": "param \"lang\" is never used in checks, check errors or redirect templates"
+    }
+  ]
+}
+`,
+		},
+		{
+			description: "writes junit report",
+			format:      testreport.FormatJUnit,
+			expectedOutput: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="murl lint" tests="2" failures="2" time="0.000">
+  <testsuite name="route [0] /issue/{id} (Issue)" tests="1" failures="1" time="0.000">
+    <testcase name="missing-tests" classname="/issue/{id}" time="0.000">
+      <failure message="route has no tests" type="LintFinding">route has no tests</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="route [2] /docs" tests="1" failures="1" time="0.000">
+    <testcase name="unused-param" classname="/docs" time="0.000">
+      <failure message="param &#34;lang&#34; is never used in checks, check errors or redirect templates" type="LintFinding">param &#34;lang&#34; is never used in checks, check errors or redirect templates</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := &bytes.Buffer{}
+			if err := testreport.WriteFindings(buffer, test.format, findings); err != nil {
+				t.Fatalf("expected write to succeed but got error: %v", err)
+			}
+
+			if buffer.String() != test.expectedOutput {
+				t.Fatalf("expected output\n%s\nbut got\n%s", test.expectedOutput, buffer.String())
+			}
+		})
+	}
+}
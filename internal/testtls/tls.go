@@ -23,6 +23,10 @@ type generateOptions struct {
 
 	serverCertOrganizationName string
 	serverCertCommonName       string
+
+	clientCertOrganizationName string
+	clientCertCommonName       string
+	clientCertEmailAddresses   []string
 }
 
 type certificate struct {
@@ -31,40 +35,68 @@ type certificate struct {
 	CertificateEncoded []byte
 }
 
-func CreateTestTLSCertificates(tmpDir string) (string, string, string) {
-	caFile := tmpDir + "/ca.crt"
-	certFile := tmpDir + "/tls.crt"
-	keyFile := tmpDir + "/tls.key"
-
-	options := &generateOptions{
+func defaultGenerateOptions() *generateOptions {
+	return &generateOptions{
 		dnsAddresses:               []string{"localhost"},
 		ipAddresses:                []net.IP{net.ParseIP("127.0.0.1")},
 		notBefore:                  time.Now(),
 		notAfter:                   time.Now().Add(365 * 24 * time.Hour),
 		serverCertOrganizationName: "Test",
 		serverCertCommonName:       "test_server_cert",
+		clientCertOrganizationName: "Test Clients",
+		clientCertCommonName:       "test_client_cert",
+		clientCertEmailAddresses:   []string{"client@example.com"},
 	}
+}
+
+func CreateTestTLSCertificates(tmpDir string) (string, string, string) {
+	caFile := tmpDir + "/ca.crt"
+	certFile := tmpDir + "/tls.crt"
+	keyFile := tmpDir + "/tls.key"
 
-	if err := generateCertificates(caFile, certFile, keyFile, options); err != nil {
+	if _, err := generateCertificates(caFile, certFile, keyFile, defaultGenerateOptions()); err != nil {
 		panic(fmt.Errorf("failed to generate certificates: %w", err))
 	}
 
 	return caFile, certFile, keyFile
 }
 
-func generateCertificates(caFile string, certFile string, keyFile string, options *generateOptions) error {
+// CreateTestMTLSCertificates creates the same files as CreateTestTLSCertificates and additionally a client certificate
+// and key issued by the same CA for mutual TLS.
+// Returns the CA, server certificate, server key, client certificate and client key file paths.
+func CreateTestMTLSCertificates(tmpDir string) (string, string, string, string, string) {
+	caFile := tmpDir + "/ca.crt"
+	certFile := tmpDir + "/tls.crt"
+	keyFile := tmpDir + "/tls.key"
+	clientCertFile := tmpDir + "/client.crt"
+	clientKeyFile := tmpDir + "/client.key"
+
+	options := defaultGenerateOptions()
+	caCert, err := generateCertificates(caFile, certFile, keyFile, options)
+	if err != nil {
+		panic(fmt.Errorf("failed to generate certificates: %w", err))
+	}
+
+	if err := generateClientCertificates(clientCertFile, clientKeyFile, options, caCert); err != nil {
+		panic(fmt.Errorf("failed to generate client certificates: %w", err))
+	}
+
+	return caFile, certFile, keyFile, clientCertFile, clientKeyFile
+}
+
+func generateCertificates(caFile string, certFile string, keyFile string, options *generateOptions) (*certificate, error) {
 	caCert, err := generateCA(options)
 	if err != nil {
-		return fmt.Errorf("failed to generate ca: %w", err)
+		return nil, fmt.Errorf("failed to generate ca: %w", err)
 	}
 
 	cert, err := generateServerCertificate(options, caCert.Key, caCert.Certificate)
 	if err != nil {
-		return fmt.Errorf("failed to generate server certs: %w", err)
+		return nil, fmt.Errorf("failed to generate server certs: %w", err)
 	}
 
 	if err := writePrivateKey(keyFile, cert.Key); err != nil {
-		return fmt.Errorf("failed to write private key to file: %w", err)
+		return nil, fmt.Errorf("failed to write private key to file: %w", err)
 	}
 
 	for filename, certBytes := range map[string][]byte{
@@ -72,10 +104,27 @@ func generateCertificates(caFile string, certFile string, keyFile string, option
 		caFile:   caCert.CertificateEncoded,
 	} {
 		if err := writeCertitificate(filename, certBytes); err != nil {
-			return fmt.Errorf("failed to write cert to file: %w", err)
+			return nil, fmt.Errorf("failed to write cert to file: %w", err)
 		}
 	}
 
+	return caCert, nil
+}
+
+func generateClientCertificates(certFile string, keyFile string, options *generateOptions, caCert *certificate) error {
+	cert, err := generateClientCertificate(options, caCert.Key, caCert.Certificate)
+	if err != nil {
+		return fmt.Errorf("failed to generate client certs: %w", err)
+	}
+
+	if err := writePrivateKey(keyFile, cert.Key); err != nil {
+		return fmt.Errorf("failed to write private key to file: %w", err)
+	}
+
+	if err := writeCertitificate(certFile, cert.CertificateEncoded); err != nil {
+		return fmt.Errorf("failed to write cert to file: %w", err)
+	}
+
 	return nil
 }
 
@@ -132,6 +181,24 @@ func generateServerCertificate(options *generateOptions, caKey *ecdsa.PrivateKey
 	return generateCertificate(&cert, caKey, caCert)
 }
 
+func generateClientCertificate(options *generateOptions, caKey *ecdsa.PrivateKey, caCert *x509.Certificate) (*certificate, error) {
+	cert := x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{options.clientCertOrganizationName},
+			CommonName:   options.clientCertCommonName,
+		},
+		NotBefore:             options.notBefore,
+		NotAfter:              options.notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+		EmailAddresses:        options.clientCertEmailAddresses,
+	}
+
+	return generateCertificate(&cert, caKey, caCert)
+}
+
 func generateCertificate(cert *x509.Certificate, rootKey *ecdsa.PrivateKey, parent *x509.Certificate) (*certificate, error) {
 	serialNumber, err := generateSerialNumber()
 	if err != nil {